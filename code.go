@@ -0,0 +1,148 @@
+package errors
+
+/*
+	error codes - registered categories of errors
+*/
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+)
+
+// Code is a category of errors, e.g. to report it to a client of an api.
+// Codes are created with RegisterCode, so each code has unique name and id.
+type Code struct {
+	name        string
+	id          int
+	description string
+	httpStatus  int
+}
+
+func (c *Code) Name() string {
+	return c.name
+}
+
+func (c *Code) ID() int {
+	return c.id
+}
+
+func (c *Code) Description() string {
+	return c.description
+}
+
+// HTTPStatus returns default http status of errors with this code.
+func (c *Code) HTTPStatus() int {
+	return c.httpStatus
+}
+
+func (c *Code) String() string {
+	return c.name
+}
+
+// MarshalJSON encodes the code as its name, so that DecodeE restores it if it's registered with the same name.
+func (c *Code) MarshalJSON() ([]byte, error) {
+	return json.Marshal(c.name)
+}
+
+var codes = struct {
+	sync.RWMutex
+	byName map[string]*Code
+	byID   map[int]*Code
+	list   []*Code
+}{
+	byName: make(map[string]*Code),
+	byID:   make(map[int]*Code),
+}
+
+// RegisterCode creates new code. Panics if code with the same name or id is already registered.
+// Usually it's called in the initialization part of a program:
+//
+//	var CodeTaskNotFound = RegisterCode("task_not_found", 1001, "task is not found", http.StatusNotFound)
+func RegisterCode(name string, id int, description string, httpStatus int) *Code {
+	codes.Lock()
+	defer codes.Unlock()
+
+	if c, ok := codes.byName[name]; ok {
+		panic(fmt.Sprintf("errors: code %q is already registered with id %d", name, c.id))
+	}
+	if c, ok := codes.byID[id]; ok {
+		panic(fmt.Sprintf("errors: code id %d is already registered for %q", id, c.name))
+	}
+
+	c := &Code{
+		name:        name,
+		id:          id,
+		description: description,
+		httpStatus:  httpStatus,
+	}
+	codes.byName[name] = c
+	codes.byID[id] = c
+	codes.list = append(codes.list, c)
+	return c
+}
+
+// LookupCode returns registered code by its name.
+func LookupCode(name string) (*Code, bool) {
+	codes.RLock()
+	defer codes.RUnlock()
+	c, ok := codes.byName[name]
+	return c, ok
+}
+
+// LookupCodeByID returns registered code by its id.
+func LookupCodeByID(id int) (*Code, bool) {
+	codes.RLock()
+	defer codes.RUnlock()
+	c, ok := codes.byID[id]
+	return c, ok
+}
+
+// Codes returns all registered codes in order of registration.
+func Codes() []*Code {
+	codes.RLock()
+	defer codes.RUnlock()
+	return append([]*Code(nil), codes.list...)
+}
+
+var codeKey = &Key[*Code]{name: "errors.code", decode: decodeCode}
+
+func init() {
+	SetValuePolicy(codeKey, KeepOutermost)
+	RegisterKey(codeKey)
+}
+
+// decodeCode restores code encoded by its name. Code must be registered with the same name.
+func decodeCode(data []byte) (*Code, error) {
+	var name string
+	if err := json.Unmarshal(data, &name); err != nil {
+		return nil, err
+	}
+	c, ok := LookupCode(name)
+	if !ok {
+		return nil, fmt.Errorf("errors: code %q is not registered", name)
+	}
+	return c, nil
+}
+
+// WrapCodeE returns error with err wrapped in and code added.
+// returnederr.Error() will be the same as err.Error().
+// If err is nil returns nil.
+// UnwrapE(returnederr) == err.
+// Code can be retrieved with the CodeE. If several codes are added, the outermost one is returned.
+func WrapCodeE(err error, c *Code) error {
+	return WithValue(err, codeKey, c)
+}
+
+func OCode(c *Code) OptionE {
+	return func(err error, _ int) error {
+		return WrapCodeE(err, c)
+	}
+}
+
+// Gets the nearest code of err, i.e. the outermost one. Returns nil if err has no code.
+// Code of an error restored by DecodeE is found as well, if it's registered with the same name.
+func CodeE(err error) *Code {
+	c, _ := Value(err, codeKey)
+	return c
+}
@@ -0,0 +1,54 @@
+package errors
+
+import (
+	"io"
+	"net/http"
+	"testing"
+)
+
+var (
+	codeTestNotFound = RegisterCode("test_not_found", -1, "not found", http.StatusNotFound)
+	codeTestInternal = RegisterCode("test_internal", -2, "internal", http.StatusInternalServerError)
+)
+
+func TestCode(t *testing.T) {
+	notFound, internal := codeTestNotFound, codeTestInternal
+
+	for _, register := range []func(){
+		func() { RegisterCode("test_not_found", -3, "", 0) },
+		func() { RegisterCode("test_other", -1, "", 0) },
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Error("Duplicate registration haven't panicked")
+				}
+			}()
+			register()
+		}()
+	}
+
+	if c, ok := LookupCode("test_not_found"); !ok || c != notFound {
+		t.Errorf("LookupCode failed: %v", c)
+	}
+	if c, ok := LookupCodeByID(-2); !ok || c != internal {
+		t.Errorf("LookupCodeByID failed: %v", c)
+	}
+	if notFound.Name() != "test_not_found" || notFound.ID() != -1 ||
+		notFound.Description() != "not found" || notFound.HTTPStatus() != http.StatusNotFound {
+		t.Errorf("Wrong code: %#v", notFound)
+	}
+
+	if c := CodeE(io.EOF); c != nil {
+		t.Errorf("Code of error without code: %v", c)
+	}
+	err := WrapE(io.EOF, OCode(notFound), OAnno("a"), OCode(internal))
+	if c := CodeE(err); c != internal {
+		t.Errorf("Wrong code: got %v, want %v", c, internal)
+	}
+
+	decoded, _ := DecodeE(mustEncodeJSON(t, err))
+	if c := CodeE(decoded); c != internal {
+		t.Errorf("Wrong code of decoded error: got %v, want %v", c, internal)
+	}
+}
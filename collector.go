@@ -0,0 +1,53 @@
+package errors
+
+import "sync"
+
+// Collector collects errors to report all of them at once, e.g. in batch jobs, which shouldn't stop on first error.
+// It's safe for concurrent use. The zero value is ready to use.
+type Collector struct {
+	mu   sync.Mutex
+	errs []error
+}
+
+// Add adds err to collected ones. nil err is ignored.
+// opts allows to add context to the error.
+func (c *Collector) Add(err error, opts ...OptionE) {
+	if err == nil {
+		return
+	}
+	err = ToSkipE(1).WrapE(err, opts...)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.errs = append(c.errs, err)
+}
+
+// Len returns number of collected errors.
+func (c *Collector) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.errs)
+}
+
+// Errors returns collected errors in order of adding.
+func (c *Collector) Errors() []error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]error(nil), c.errs...)
+}
+
+// Err returns nil if no errors are collected.
+// Otherwise returns the first collected error with the rest of them suppressed by WrapSuppressedVisibleE.
+// So SprintE prints all of them, SuppressedE lists the rest of them in order of adding
+// and IsE/AsE match any of them.
+func (c *Collector) Err() error {
+	errs := c.Errors()
+	if len(errs) == 0 {
+		return nil
+	}
+	err := errs[0]
+	for i := len(errs) - 1; i > 0; i-- {
+		err = WrapSuppressedVisibleE(err, errs[i])
+	}
+	return err
+}
@@ -23,10 +23,15 @@ One can add an annotation to an error. Moreover, if error already has a stacktra
 along with the corresponding line in stacktrace, so it's easy to find in which function this annotations was added.
 
 One can suppress an existing error with newer one. Both errors will be printed.
+By default only the newer one is matched by IsE and AsE, WrapSuppressedVisibleE makes both of them matched.
 
 One can add a value to an error. Later this value can be retrieved with the key used to add this value. If several
-values are added with the same key, only the first one (from the most deep stack level) is saved. It might be
+values are added with the same key, by default only the first one (from the most deep stack level) is saved. It might be
 very useful to pass logger to the function upper in the stack. Look at the example to see this approach.
+SetValuePolicy allows to keep the outermost value or all of them instead.
+
+Errors wrapping several errors, e.g. created by errors.Join, are supported as well. Each of the wrapped errors
+is printed with its own context, and getters like ValueE search through all of them.
 
 About second point:
 
@@ -54,7 +59,7 @@ error handling approach, which is quite similar to exception approach.
 This package provides similar to this Handle-Check mechanism, based on panics.
 Details are below =)
 
-Example of error format, when printed with SprintE:
+Example of error format, when printed with SprintE (or fmt's %+v verb):
 
     ERROR:
     task can not be marked as done
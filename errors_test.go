@@ -1,10 +1,15 @@
 package errors
 
 import (
+	"context"
 	"database/sql"
+	stderrors "errors"
 	"fmt"
 	"io"
+	"runtime"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestWrapNil(t *testing.T) {
@@ -81,3 +86,470 @@ func TestWrap(t *testing.T) {
 		}
 	}
 }
+
+func TestStack(t *testing.T) {
+	if s := StackE(io.EOF); s != nil {
+		t.Errorf("StackE of error without stack: got %v, want nil", s)
+	}
+
+	err := WrapE(io.EOF, OAnno("a"), OStack())
+	s := StackE(err)
+	if len(s) == 0 {
+		t.Fatal("StackE returned empty stack")
+	}
+	if s[0].Function != "github.com/pashaosipyants/errors/v2.TestStack" {
+		t.Errorf("Wrong function of the first frame: got %q", s[0].Function)
+	}
+	if s[0].Package != "github.com/pashaosipyants/errors/v2" {
+		t.Errorf("Wrong package of the first frame: got %q", s[0].Package)
+	}
+	if !strings.HasSuffix(s[0].File, "errors_test.go") || s[0].Line == 0 {
+		t.Errorf("Wrong location of the first frame: got %s:%d", s[0].File, s[0].Line)
+	}
+}
+
+func TestFuncPackage(t *testing.T) {
+	tests := []struct {
+		name, want string
+	}{
+		{"main.main", "main"},
+		{"testing.(*M).Run", "testing"},
+		{"github.com/pashaosipyants/errors/v2_test.Example.func1", "github.com/pashaosipyants/errors/v2_test"},
+		{"github.com/pashaosipyants/errors/v2.(*_errorStack).Unwrap", "github.com/pashaosipyants/errors/v2"},
+	}
+	for _, tt := range tests {
+		if got := funcPackage(tt.name); got != tt.want {
+			t.Errorf("funcPackage(%q): got %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func recursiveStack(depth int) error {
+	if depth == 0 {
+		return WrapStackE(io.EOF)
+	}
+	return recursiveStack(depth - 1)
+}
+
+func TestStackPolicy(t *testing.T) {
+	defer SetStackPolicy(GetStackPolicy())
+
+	if got := len(StackE(recursiveStack(ErrStackMaxDepth * 2))); got != ErrStackMaxDepth {
+		t.Errorf("Wrong default stack depth: got %d, want %d", got, ErrStackMaxDepth)
+	}
+
+	// depth of truncated stack is unknown, annotations are matched by function then
+	err := recursiveAnnotated(ErrStackMaxDepth * 2)
+	if chain, _ := chainE(err); stackOf(chain) == nil || stackOf(chain).depth != 0 {
+		t.Errorf("Depth of truncated stack is known: %#v", err)
+	}
+	if sprint := SprintE(err); strings.Contains(sprint, "ELSE ANNOTATIONS") {
+		t.Errorf("Annotations are not matched with truncated stack:\n%s", sprint)
+	}
+
+	SetStackPolicy(StackPolicy{MaxDepth: ErrStackMaxDepth * 4})
+	if got := len(StackE(recursiveStack(ErrStackMaxDepth * 2))); got <= ErrStackMaxDepth*2 {
+		t.Errorf("Stack is not deep enough: got %d", got)
+	}
+
+	SetStackPolicy(StackPolicy{Disabled: true})
+	if err := WrapStackE(io.EOF); err != io.EOF {
+		t.Errorf("Stack is recorded while disabled")
+	}
+	if errAnno := WrapAnnotationE(io.EOF, "anno").(*_errorAnnotation); errAnno.depth != 0 {
+		t.Errorf("Depth of annotation is computed while stack is disabled")
+	}
+
+	SetStackPolicy(StackPolicy{
+		Disabled: true,
+		Packages: map[string]StackPolicy{"github.com/pashaosipyants/errors/v2": {MaxDepth: 2}},
+	})
+	if got := len(StackE(recursiveStack(5))); got != 2 {
+		t.Errorf("Package policy is not applied: got depth %d, want 2", got)
+	}
+}
+
+func TestFormat(t *testing.T) {
+	errs := []error{
+		WrapStackE(io.EOF),
+		WrapAnnotationE(io.EOF, "anno"),
+		WrapSuppressedE(io.EOF, sql.ErrNoRows),
+		WrapValueE(io.EOF, "a", "b"),
+	}
+	for _, err := range errs {
+		for _, verb := range []string{"%v", "%s"} {
+			if got := fmt.Sprintf(verb, err); got != "EOF" {
+				t.Errorf("Wrong %s of %T: got %q, want %q", verb, err, got, "EOF")
+			}
+		}
+		if got := fmt.Sprintf("%q", err); got != `"EOF"` {
+			t.Errorf("Wrong %%q of %T: got %s", err, got)
+		}
+		if got := fmt.Sprintf("%+v", err); got != SprintE(err) {
+			t.Errorf("Wrong %%+v of %T: got %q, want %q", err, got, SprintE(err))
+		}
+		if got := fmt.Sprintf("%#v", err); !strings.HasPrefix(got, "&"+fmt.Sprintf("%T", err)[1:]+"{error:&errors.errorString{") {
+			t.Errorf("Wrong %%#v of %T: got %s", err, got)
+		}
+	}
+}
+
+func recursiveAnnotated(depth int) error {
+	if depth == 0 {
+		return WrapStackE(io.EOF)
+	}
+	err := recursiveAnnotated(depth - 1)
+	err = WrapAnnotationE(err, fmt.Sprintf("level %d a", depth))
+	return WrapAnnotationE(err, fmt.Sprintf("level %d b", depth))
+}
+
+func TestAnnotationsInRecursion(t *testing.T) {
+	sprint := SprintE(recursiveAnnotated(3))
+
+	if strings.Contains(sprint, "ELSE ANNOTATIONS") {
+		t.Errorf("Annotations are not matched with stack:\n%s", sprint)
+	}
+
+	// annotations of each invocation follow its frame(function name and file:line)
+	var got []string
+	lines := strings.Split(sprint, "\n")
+	for i, line := range lines {
+		if line != "github.com/pashaosipyants/errors/v2.recursiveAnnotated" {
+			continue
+		}
+		var annos []string
+		for _, l := range lines[i+2:] {
+			if !strings.HasPrefix(l, "\tANNOTATION: ") {
+				break
+			}
+			annos = append(annos, strings.TrimPrefix(l, "\tANNOTATION: "))
+		}
+		got = append(got, strings.Join(annos, ", "))
+	}
+	want := []string{"", "level 1 a, level 1 b", "level 2 a, level 2 b", "level 3 a, level 3 b"}
+	if fmt.Sprintf("%q", got) != fmt.Sprintf("%q", want) {
+		t.Errorf("Wrong annotations: got %q, want %q", got, want)
+	}
+}
+
+func TestAnnotations(t *testing.T) {
+	if annos := AnnotationsE(io.EOF); len(annos) != 0 {
+		t.Errorf("AnnotationsE of error without annotations: got %v, want empty", annos)
+	}
+
+	_, file, line, _ := runtime.Caller(0)
+	err := WrapE(io.EOF, OStack(), OAnno("first"))
+	func() {
+		err = WrapAnnotationE(err, "in closure")
+	}()
+	err = WrapAnnotationE(err, "second")
+
+	want := []Annotation{
+		{"first", "github.com/pashaosipyants/errors/v2.TestAnnotations", file, line + 1},
+		{"in closure", "github.com/pashaosipyants/errors/v2.TestAnnotations.func1", file, line + 3},
+		{"second", "github.com/pashaosipyants/errors/v2.TestAnnotations", file, line + 5},
+	}
+	if got := AnnotationsE(err); fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("Wrong annotations:\ngot  %v\nwant %v", got, want)
+	}
+
+	sprint := SprintE(err)
+	closureLocation := fmt.Sprintf("\nELSE ANNOTATIONS:\ngithub.com/pashaosipyants/errors/v2.TestAnnotations.func1\n\t%s:%d\n\tANNOTATION: in closure", file, line+3)
+	if !strings.Contains(sprint, closureLocation) {
+		t.Errorf("Closure annotation is printed without location:\n%s", sprint)
+	}
+}
+
+func TestKey(t *testing.T) {
+	intKey, otherIntKey := NewKey[int]("key"), NewKey[int]("key")
+	errKey := NewKey[error]("err")
+
+	err := WrapE(io.EOF, OKeyValue(intKey, 1), OValue("key", "string"))
+	err = WithValue(err, errKey, nil)
+
+	if v, found := Value(err, intKey); !found || v != 1 {
+		t.Errorf("Wrong value: got %v, %v, want %v, %v", v, found, 1, true)
+	}
+	if v, found := Value(err, otherIntKey); found || v != 0 {
+		t.Errorf("Keys with the same name collide: got %v, %v", v, found)
+	}
+	if v, found := Value(err, errKey); !found || v != nil {
+		t.Errorf("Wrong nil value: got %v, %v, want %v, %v", v, found, nil, true)
+	}
+	if v := ValueE(err, intKey); v != 1 {
+		t.Errorf("Wrong ValueE by typed key: got %v, want %v", v, 1)
+	}
+	if v := ValueE(err, "key"); v != "string" {
+		t.Errorf("Typed key collides with string key: got %v", v)
+	}
+
+	decoded, _ := DecodeE(mustEncodeJSON(t, WithValue(io.EOF, intKey, 1)))
+	if v := ValueE(decoded, "key"); v != 1.0 {
+		t.Errorf("Typed key is not decoded as its name: got %v", v)
+	}
+
+	RegisterKey(registeredIntKey)
+	RegisterKey(registeredIntKey) // the same registration is allowed
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("Duplicate registration haven't panicked")
+			}
+		}()
+		RegisterKey(NewKey[int](registeredIntKey.String()))
+	}()
+
+	err = WrapE(io.EOF, OKeyValue(registeredIntKey, 1), OValue(registeredIntKey.String(), "string"))
+	decoded = mustDecodeE(t, mustEncodeJSON(t, err))
+	if v, found := Value(decoded, registeredIntKey); !found || v != 1 {
+		t.Errorf("Wrong value of registered key after decoding: got %v, %v", v, found)
+	}
+	if v := ValueE(decoded, registeredIntKey.String()); v != "string" {
+		t.Errorf("Registered key collides with string key after decoding: got %v", v)
+	}
+}
+
+var registeredIntKey = NewKey[int]("errors_test.registered")
+
+func TestValues(t *testing.T) {
+	if values := ValuesE(io.EOF); len(values) != 0 {
+		t.Errorf("ValuesE of error without values: got %v, want empty", values)
+	}
+
+	inner := WrapE(io.EOF, OValue("a", 1), OValue("b", 2))
+	// WrapValueE doesn't add value with existing key, so the chain is built manually
+	err := &_errorValue{error: fmt.Errorf("wrapped: %w", inner), key: "a", value: 3}
+
+	want := []KeyValue{
+		{"a", 3, false},
+		{"b", 2, false},
+		{"a", 1, true},
+	}
+	if got := ValuesE(err); fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("Wrong values:\ngot  %v\nwant %v", got, want)
+	}
+}
+
+func TestValuePolicy(t *testing.T) {
+	type outermost struct{}
+	SetValuePolicy(outermost{}, KeepOutermost)
+	type appended struct{}
+	SetValuePolicy(appended{}, AppendValues)
+
+	tests := []struct {
+		opts []OptionE
+		key  interface{}
+		want interface{}
+	}{
+		{[]OptionE{OValue("a", 1), OValue("a", 2)}, "a", 1},
+		{[]OptionE{OValue("a", 1), OValuePolicy("a", 2, KeepOutermost)}, "a", 2},
+		{[]OptionE{OValue("a", 1), OValuePolicy("a", 2, AppendValues), OValuePolicy("a", 3, AppendValues)},
+			"a", []interface{}{1, 2, 3}},
+		{[]OptionE{OValuePolicy("a", 1, AppendValues), OValuePolicy("a", 2, KeepOutermost)}, "a", 2},
+		{[]OptionE{OValuePolicy("a", 1, AppendValues), OValue("a", 2)}, "a", []interface{}{1}},
+		{[]OptionE{OValue(outermost{}, 1), OValue(outermost{}, 2)}, outermost{}, 2},
+		{[]OptionE{OValue(outermost{}, 1), OValuePolicy(outermost{}, 2, KeepDeepest)}, outermost{}, 1},
+		{[]OptionE{OValue(appended{}, 1), OAnno("a"), OValue(appended{}, 2)}, appended{}, []interface{}{1, 2}},
+	}
+
+	for i, tt := range tests {
+		err := WrapE(io.EOF, tt.opts...)
+		if got := ValueE(err, tt.key); fmt.Sprint(got) != fmt.Sprint(tt.want) {
+			t.Errorf("%d: wrong value: got %v, want %v", i, got, tt.want)
+		}
+	}
+}
+
+func TestValueNotComparableKey(t *testing.T) {
+	key := []int{1}
+	err := WrapE(io.EOF, OValue(key, 1), OValue("a", 2))
+	if values := ValuesE(err); len(values) != 2 || values[1].Value != 1 || values[1].Shadowed {
+		t.Errorf("Wrong values: %v", values)
+	}
+
+	err = WrapE(io.EOF, OValue([]int{1}, 1), OValue([]int{2}, 2))
+	if values := ValuesE(err); len(values) != 2 {
+		t.Errorf("Wrong values with keys of the same not comparable type: %v", values)
+	}
+	if v := ValueE(err, []int{1}); v != nil {
+		t.Errorf("Value is found by not comparable key: %v", v)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("SetValuePolicy doesn't panic on not comparable key")
+		}
+	}()
+	SetValuePolicy(key, AppendValues)
+}
+
+func TestSuppressedVisibility(t *testing.T) {
+	err := WrapE(io.EOF, OSupp(io.ErrUnexpectedEOF), OSuppVisible(WrapStackE(sql.ErrNoRows)))
+
+	if !IsE(err, io.EOF) || !IsE(err, sql.ErrNoRows) || IsE(err, io.ErrUnexpectedEOF) {
+		t.Error("IsE failed")
+	}
+	if !IsPrimaryE(err, io.EOF) || IsPrimaryE(err, sql.ErrNoRows) {
+		t.Error("IsPrimaryE failed")
+	}
+
+	var errStack *_errorStack
+	if !AsE(err, &errStack) || errStack.error != sql.ErrNoRows {
+		t.Error("AsE failed")
+	}
+	if AsPrimaryE(err, &errStack) {
+		t.Error("AsPrimaryE failed")
+	}
+	if StackE(err) != nil {
+		t.Error("Stack of visible suppressed error is considered as a stack of the primary one")
+	}
+
+	decoded, _ := DecodeE(mustEncodeJSON(t, err))
+	s := SuppressedE(decoded)
+	if len(s) != 2 || s[0].Error() != sql.ErrNoRows.Error() || s[1].Error() != io.ErrUnexpectedEOF.Error() {
+		t.Errorf("Wrong suppressed of decoded error: %v", s)
+	}
+	var errSupp *_errorSuppressed
+	if !AsPrimaryE(decoded, &errSupp) || !errSupp.visible {
+		t.Error("Visibility of suppressed error is not decoded")
+	}
+}
+
+func TestJoined(t *testing.T) {
+	first := WrapE(io.EOF, OStack(), OAnno("first"), OValue("a", 1), OSupp(io.ErrClosedPipe))
+	second := WrapE(sql.ErrNoRows, OAnno("second"), OValue("b", 2), OValue("a", 3))
+	err := WrapE(stderrors.Join(first, second), OStack(), OAnno("joined"), OValue("c", 4))
+
+	if ValueE(err, "a") != 1 || ValueE(err, "b") != 2 || ValueE(err, "c") != 4 {
+		t.Errorf("Wrong values: %v", ValuesE(err))
+	}
+	wantValues := []KeyValue{{"c", 4, false}, {"a", 1, false}, {"a", 3, true}, {"b", 2, false}}
+	if got := ValuesE(err); fmt.Sprint(got) != fmt.Sprint(wantValues) {
+		t.Errorf("Wrong values:\ngot  %v\nwant %v", got, wantValues)
+	}
+	if s := SuppressedE(err); len(s) != 1 || s[0] != io.ErrClosedPipe {
+		t.Errorf("Wrong suppressed: %v", s)
+	}
+	var annos []string
+	for _, a := range AnnotationsE(err) {
+		annos = append(annos, a.Message)
+	}
+	if fmt.Sprint(annos) != "[first second joined]" {
+		t.Errorf("Wrong annotations: %v", annos)
+	}
+	if !IsPrimaryE(err, sql.ErrNoRows) {
+		t.Error("IsPrimaryE failed")
+	}
+
+	sprint := SprintE(err)
+	for _, want := range []string{
+		"\nJOINED:\nERROR:\nEOF\n\nSTACK:\n",
+		"\tANNOTATION: first\n",
+		"\nJOINED:\nERROR:\nsql: no rows in result set\n\nELSE ANNOTATIONS:\n",
+		"\tANNOTATION: second",
+		"\tANNOTATION: joined\n",
+		"\nSUPPRESSED:\nERROR:\nio: read/write on closed pipe\n",
+	} {
+		if !strings.Contains(sprint, want) {
+			t.Errorf("SprintE doesn't contain %q:\n%s", want, sprint)
+		}
+	}
+	if strings.Count(sprint, "STACK:") != 2 {
+		t.Errorf("Stacks of the joined error are not printed separately:\n%s", sprint)
+	}
+
+	decoded, errDec := DecodeE(mustEncodeJSON(t, err))
+	if errDec != nil {
+		t.Fatalf("DecodeE failed: %v", errDec)
+	}
+	if ValueE(decoded, "b") != 2.0 || len(SuppressedE(decoded)) != 1 ||
+		strings.Count(SprintE(decoded), "REMOTE STACK:") != 2 {
+		t.Errorf("Joined errors are not decoded:\n%s", SprintE(decoded))
+	}
+}
+
+func TestCollector(t *testing.T) {
+	var c Collector
+	if c.Err() != nil || c.Len() != 0 {
+		t.Error("Empty collector is not empty")
+	}
+
+	c.Add(nil)
+	c.Add(io.EOF)
+	c.Add(sql.ErrNoRows, OAnno("anno"))
+	c.Add(io.ErrUnexpectedEOF)
+	if c.Len() != 3 || len(c.Errors()) != 3 {
+		t.Errorf("Wrong number of collected errors: got %d, want %d", c.Len(), 3)
+	}
+
+	err := c.Err()
+	if err.Error() != io.EOF.Error() {
+		t.Errorf("Wrong message: got %q, want %q", err.Error(), io.EOF.Error())
+	}
+	if !IsE(err, io.EOF) || !IsE(err, sql.ErrNoRows) || !IsE(err, io.ErrUnexpectedEOF) {
+		t.Error("IsE doesn't match collected errors")
+	}
+	if s := SuppressedE(err); len(s) != 2 || !IsE(s[0], sql.ErrNoRows) || s[1] != io.ErrUnexpectedEOF {
+		t.Errorf("Wrong suppressed: %v", s)
+	}
+	if !strings.Contains(SprintE(err), "ANNOTATION: anno") {
+		t.Errorf("Context of collected error is not printed:\n%s", SprintE(err))
+	}
+}
+
+func TestAllFuncE(t *testing.T) {
+	var executed int
+	f := func(err error) func() error {
+		return func() error {
+			executed++
+			return err
+		}
+	}
+
+	if err := AllFuncE(f(nil), f(nil)); err != nil || executed != 2 {
+		t.Errorf("Wrong result without errors: %v, executed %d", err, executed)
+	}
+
+	executed = 0
+	err := AllFuncE(f(nil), f(io.EOF), f(nil), f(sql.ErrNoRows))
+	if executed != 4 {
+		t.Errorf("Not all funcs are executed: %d", executed)
+	}
+	if !IsPrimaryE(err, io.EOF) || !IsE(err, sql.ErrNoRows) {
+		t.Errorf("Wrong error: %v", err)
+	}
+}
+
+func TestAnyFuncParallelE(t *testing.T) {
+	ok := func(context.Context) error { return nil }
+	fail := func(err error, delay time.Duration) func(context.Context) error {
+		return func(context.Context) error {
+			time.Sleep(delay)
+			return err
+		}
+	}
+	waitCancel := func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+
+	if err := AnyFuncParallelE(context.Background(), ok, ok); err != nil {
+		t.Errorf("Wrong result without errors: %v", err)
+	}
+
+	err := AnyFuncParallelE(context.Background(),
+		ok, fail(io.EOF, 0), fail(sql.ErrNoRows, 50*time.Millisecond), waitCancel)
+	if !IsE(err, io.EOF) {
+		t.Errorf("Wrong error: got %v, want %v", err, io.EOF)
+	}
+	if s := SuppressedE(err); len(s) != 1 || s[0] != sql.ErrNoRows {
+		t.Errorf("Wrong suppressed: %v", s)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err = AnyFuncParallelE(ctx, waitCancel, waitCancel)
+	if !IsE(err, context.Canceled) || len(SuppressedE(err)) != 1 {
+		t.Errorf("Wrong error of canceled context: %v, suppressed %v", err, SuppressedE(err))
+	}
+}
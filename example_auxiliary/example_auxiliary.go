@@ -14,6 +14,16 @@ var (
 	ErrTaskAlreadyExistAndDone    = NewE("already_exist_and_done")
 )
 
+// Key of the logger with relevant fields, which is added to errors.
+var LoggerKey = NewKey[*logrus.Entry]("logger")
+
+// Register errors, so that they can be checked with IsE after being transferred with EncodeJSON/DecodeE.
+func init() {
+	RegisterSentinelE("example_auxiliary.ErrConnectionFailed", ErrConnectionFailed)
+	RegisterSentinelE("example_auxiliary.ErrTaskAlreadyExistButNotDone", ErrTaskAlreadyExistButNotDone)
+	RegisterSentinelE("example_auxiliary.ErrTaskAlreadyExistAndDone", ErrTaskAlreadyExistAndDone)
+}
+
 // It mocks case when one tries to save task to db, but connection error occurs.
 //
 // It returns specific error with stacktrace and with logger inside, which contains relevant fields.
@@ -21,7 +31,7 @@ func SaveTaskToDbMockConnectionError(l *logrus.Entry) error {
 	// db work
 
 	l = l.WithField("reason", "connection")
-	return WrapE(ErrConnectionFailed, OStack(), OValue("logger", l))
+	return WrapE(ErrConnectionFailed, OStack(), OKeyValue(LoggerKey, l))
 }
 
 // It mocks case when one tries to save task to db, but it already exists.
@@ -33,7 +43,7 @@ func SaveTaskToDbMockExistButNotDone(l *logrus.Entry) error {
 	// ask another service if task is already done - false
 
 	l = l.WithField("reason", "duplicate/notDone")
-	return WrapE(ErrTaskAlreadyExistButNotDone, OStack(), OValue("logger", l))
+	return WrapE(ErrTaskAlreadyExistButNotDone, OStack(), OKeyValue(LoggerKey, l))
 }
 
 // It mocks case when one tries to save task to db, but it already exists.
@@ -45,7 +55,7 @@ func SaveTaskToDbMockExistAndDone(l *logrus.Entry) error {
 	// ask another service if task is already done - true
 
 	l = l.WithField("reason", "duplicate/Done")
-	return WrapE(ErrTaskAlreadyExistAndDone, OStack(), OValue("logger", l))
+	return WrapE(ErrTaskAlreadyExistAndDone, OStack(), OKeyValue(LoggerKey, l))
 }
 
 // It mocks case of successful saving task.
@@ -64,9 +74,7 @@ func SaveTaskToDbMockSuccess() error {
 func CreateTaskInitedByUser(l *logrus.Entry, i, userID int) (reterr error) {
 	l = l.WithField("service", "database")
 
-	defer Handler(func(err error) {
-		reterr = WrapE(err, OValue("logger", l)) // actually not set, because it's set inside further functions with more specific fields
-	})
+	defer AnnotateHandler(&reterr, OKeyValue(LoggerKey, l))() // actually not set, because it's set inside further functions with more specific fields
 
 	switch i {
 	case 0:
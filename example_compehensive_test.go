@@ -3,6 +3,7 @@ package errors_test
 import (
 	"bytes"
 	"fmt"
+	"net/http"
 	"os"
 
 	"github.com/sirupsen/logrus"
@@ -33,9 +34,9 @@ func Example() {
 
 			// smth like catch block
 			defer Handler(func(err error) {
-				switch ValueE(err, "api") {
-				case errcode_apicreatetaskfailed:
-					logger, ok := ValueE(err, "logger").(*logrus.Entry) // logger with relevant fields of functions deeper in the call stack
+				switch CodeE(err) {
+				case codeAPICreateTaskFailed:
+					logger, ok := Value(err, example_auxiliary.LoggerKey) // logger with relevant fields of functions deeper in the call stack
 					if !ok {
 						logger = l
 					}
@@ -45,7 +46,7 @@ func Example() {
 					}
 					logger.Error(SprintE(err)) // log
 					// may be some specific actions
-				case errcode_apiuserloginfailed:
+				case codeAPIUserLoginFailed:
 					// may be some specific actions
 					panic("Assertion failed") // but in our example can't be here
 				default:
@@ -54,10 +55,10 @@ func Example() {
 			})
 
 			Check(
-				apiUserLogin(l), OValue("api", errcode_apiuserloginfailed))
+				apiUserLogin(l), OCode(codeAPIUserLoginFailed))
 
 			Check(
-				apiCreateTask(l, i), OValue("api", errcode_apicreatetaskfailed))
+				apiCreateTask(l, i), OCode(codeAPICreateTaskFailed))
 
 			l.Info("Success!!!\n") // log
 		}()
@@ -67,8 +68,10 @@ func Example() {
 	// wrong output specially to make this function be executed and see output of this example
 }
 
-const errcode_apicreatetaskfailed = "api_create_task_failed"
-const errcode_apiuserloginfailed = "api_user_login_failed"
+var (
+	codeAPICreateTaskFailed = RegisterCode("api_create_task_failed", 1, "task can not be created", http.StatusInternalServerError)
+	codeAPIUserLoginFailed  = RegisterCode("api_user_login_failed", 2, "user can not log in", http.StatusUnauthorized)
+)
 
 func apiCreateTask(l *logrus.Entry, i int) (reterr error) {
 	defer Handler(func(err error) {
@@ -2,23 +2,171 @@ package errors
 
 /*
 	retrieve contexts from an error
+
+	Getters traverse the whole tree of an error, i.e. errors wrapped by Unwrap() error
+	as well as by Unwrap() []error (e.g. created by errors.Join), in depth-first order.
+	Suppressed errors are not traversed.
 */
 
 // Gets value by key saved in err. Returns nil if not found.
+// Values added with not comparable keys are never found, use ValuesE to get them.
 func ValueE(err error, key interface{}) interface{} {
-	var errVal *_errorValue
-	for errIteration := err; AsE(errIteration, &errVal); errIteration = errVal.error {
-		if errVal.key == key {
-			return errVal.value
-		}
+	value, _ := lookupValue(err, key)
+	return value
+}
+
+// lookupValue gets value by key saved in err. found reports whether the value is found.
+func lookupValue(err error, key interface{}) (value interface{}, found bool) {
+	if errVal := lookupErrValue(err, key); errVal != nil {
+		return errVal.value, true
 	}
-	return nil
+	return nil, false
+}
+
+// lookupErrValue returns the first error of err's tree which holds value for the key or nil if there is no such.
+// Not comparable key is never found.
+func lookupErrValue(err error, key interface{}) (found *_errorValue) {
+	if !isComparable(key) {
+		return nil
+	}
+	walkE(err, func(e error) bool {
+		if errVal, ok := e.(*_errorValue); ok && isComparable(errVal.key) && errVal.key == key {
+			found = errVal
+		}
+		return found == nil
+	})
+	return
+}
+
+// KeyValue is a value added to an error with its key.
+type KeyValue struct {
+	Key, Value interface{}
+	// Shadowed reports whether there is a value with the same key closer to the outermost error.
+	// ValueE never returns shadowed values. Values with not comparable keys are never shadowed.
+	Shadowed bool
+}
+
+// Gets all values saved in err in chain order, starting with the outermost one.
+func ValuesE(err error) (values []KeyValue) {
+	seen := make(map[interface{}]bool)
+	walkE(err, func(e error) bool {
+		if errVal, ok := e.(*_errorValue); ok {
+			if !isComparable(errVal.key) {
+				values = append(values, KeyValue{Key: errVal.key, Value: errVal.value})
+				return true
+			}
+			values = append(values, KeyValue{
+				Key:      errVal.key,
+				Value:    errVal.value,
+				Shadowed: seen[errVal.key],
+			})
+			seen[errVal.key] = true
+		}
+		return true
+	})
+	return
 }
 
 func SuppressedE(err error) (supps []error) {
-	var errSupp *_errorSuppressed
-	for errIteration := err; AsE(errIteration, &errSupp); errIteration = errSupp.error {
-		supps = append(supps, errSupp.suppressed)
+	walkE(err, func(e error) bool {
+		if errSupp, ok := e.(*_errorSuppressed); ok {
+			supps = append(supps, errSupp.suppressed)
+		}
+		return true
+	})
+	return
+}
+
+// Gets stacktrace recorded to err by WrapStackE. Returns nil if err has no stacktrace.
+// Frames of an error decoded by DecodeE are marked as remote.
+// If err is a tree of errors, the first found stacktrace is returned.
+func StackE(err error) (st StackTrace) {
+	walkE(err, func(e error) bool {
+		if errStack, ok := e.(*_errorStack); ok {
+			st = errStack.trace()
+		}
+		return st == nil
+	})
+	return
+}
+
+// Annotation is an additional message added to an error by WrapAnnotationE.
+type Annotation struct {
+	Message  string
+	Function string // full name of the function annotation was added in
+	File     string
+	Line     int
+}
+
+// Gets annotations added to err in order of adding, i.e. starting with the deepest one.
+// If err is a tree of errors, annotations of the branches are listed before annotations of the common part.
+func AnnotationsE(err error) (res []Annotation) {
+	chain, branches := chainE(err)
+	for _, b := range branches {
+		res = append(res, AnnotationsE(b)...)
+	}
+	annos := annotationsOf(chain)
+	for i := len(annos) - 1; i >= 0; i-- {
+		res = append(res, Annotation{
+			Message:  annos[i].annotation,
+			Function: annos[i].where,
+			File:     annos[i].file,
+			Line:     annos[i].line,
+		})
+	}
+	return res
+}
+
+// annotationsOf returns annotations of the chain in chain order, starting with the outermost one.
+func annotationsOf(chain []error) (annos []*_errorAnnotation) {
+	for _, e := range chain {
+		if errAnno, ok := e.(*_errorAnnotation); ok {
+			annos = append(annos, errAnno)
+		}
 	}
 	return
 }
+
+// stackOf returns the first error of the chain which holds stacktrace or nil if there is no such.
+func stackOf(chain []error) *_errorStack {
+	for _, e := range chain {
+		if errStack, ok := e.(*_errorStack); ok {
+			return errStack
+		}
+	}
+	return nil
+}
+
+// chainE returns err with errors wrapped in it by Unwrap() error, i.e. linear part of err's tree,
+// and errors wrapped in the last one of them by Unwrap() []error, e.g. by errors.Join.
+func chainE(err error) (chain, branches []error) {
+	for err != nil {
+		chain = append(chain, err)
+		switch x := err.(type) {
+		case interface{ Unwrap() error }:
+			err = x.Unwrap()
+		case interface{ Unwrap() []error }:
+			return chain, x.Unwrap()
+		default:
+			return chain, nil
+		}
+	}
+	return chain, nil
+}
+
+// walkE calls f for every error of err's tree in depth-first order. Suppressed errors are not traversed.
+// Stops and returns false as soon as f returns false.
+func walkE(err error, f func(error) bool) bool {
+	chain, branches := chainE(err)
+	for _, e := range chain {
+		if !f(e) {
+			return false
+		}
+	}
+	for _, b := range branches {
+		if !walkE(b, f) {
+			return false
+		}
+	}
+	return true
+}
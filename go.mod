@@ -1,5 +1,7 @@
 module github.com/pashaosipyants/errors/v2
 
-go 1.14
+go 1.20
 
 require github.com/sirupsen/logrus v1.7.0
+
+require golang.org/x/sys v0.0.0-20191026070338-33540a1f6037 // indirect
@@ -0,0 +1,44 @@
+package errors
+
+import "sync"
+
+// Group runs funcs in goroutines and handles panics made by Check...() in them.
+// Such panics can't be handled by Handler deferred in the launching function, since they are in another goroutine.
+// Errors are collected and returned by Wait, so one can deliver them to the Handler of the launching function:
+//
+//	func DoAll() (reterr error) {
+//		defer DefaultHandler(&reterr)
+//
+//		var g Group
+//		g.Go(func() { Check(DoSmth()) })
+//		g.Go(func() { Check(DoSmthElse()) })
+//		Check(g.Wait())
+//		...
+//	}
+//
+// The zero value is ready to use.
+type Group struct {
+	wg sync.WaitGroup
+	c  Collector
+}
+
+// Go runs f in a new goroutine. Panic made by Check...() in f is handled, its error is returned by Wait.
+// Other panics are not intercepted.
+func (g *Group) Go(f func()) {
+	g.wg.Add(1)
+	go func() {
+		defer g.wg.Done()
+		defer Handler(func(err error) {
+			g.c.Add(err)
+		})
+
+		f()
+	}()
+}
+
+// Wait waits for all funcs run by Go to finish.
+// Returns nil if there are no errors, otherwise the first one with the rest of them suppressed. See Collector.Err.
+func (g *Group) Wait() error {
+	g.wg.Wait()
+	return g.c.Err()
+}
@@ -1,5 +1,10 @@
 package errors
 
+import (
+	"fmt"
+	"runtime"
+)
+
 // Check panics with an error.
 // One can handle this panic defering Handler func.
 // opts allows to add context to the error. OStack is enabled by default.
@@ -20,6 +25,31 @@ func CheckIf(ifErr bool, err error, opts ...OptionE) {
 	}
 }
 
+// Check1 is the same as Check, but returns v if err is nil. It allows to check a function call right away:
+//
+//	x := Check1(DoSmth())
+//
+// Notice that go doesn't allow to pass opts along with the result of a function call.
+// To add context one has to pass values explicitly: v := Check1(x, err, OAnno(text)).
+func Check1[T any](v T, err error, opts ...OptionE) T {
+	if err != nil {
+		opts = append(opts, OStack())
+		panic(handleable{ToSkipE(1).WrapE(err, opts...)})
+	}
+	return v
+}
+
+// Check2 is the same as Check1, but for functions returning two values and an error.
+//
+//	x, y := Check2(DoSmth())
+func Check2[T1, T2 any](v1 T1, v2 T2, err error, opts ...OptionE) (T1, T2) {
+	if err != nil {
+		opts = append(opts, OStack())
+		panic(handleable{ToSkipE(1).WrapE(err, opts...)})
+	}
+	return v1, v2
+}
+
 // The same as Check, but OStack is not enabled by default.
 func CheckNoStack(err error, opts ...OptionE) {
 	if err != nil {
@@ -71,3 +101,113 @@ func DefaultHandler(reterr *error, elseDefer ...func()) {
 		panic(r)
 	}
 }
+
+// PanicError is an error made of an ordinary panic(not by Check...()) by HandlerRecoverAll and DefaultHandlerRecoverAll.
+// It has stacktrace of the place panic occurred in.
+type PanicError struct {
+	Value interface{} // value panic was called with
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic: %v", e.Value)
+}
+
+// Unwrap returns Value if it's an error, e.g. runtime.Error. Otherwise returns nil.
+func (e *PanicError) Unwrap() error {
+	err, _ := e.Value.(error)
+	return err
+}
+
+// as Handler, but ordinary panics are handled as well. Such panic is converted to an error of type *PanicError
+// with stacktrace of the place panic occurred in.
+func HandlerRecoverAll(handle func(err error), elseDefer ...func()) {
+	switch r := recover().(type) {
+	case nil:
+		for _, f := range elseDefer {
+			f()
+		}
+	case handleable:
+		handle(r.err)
+	default:
+		handle(newPanicError(r))
+	}
+}
+
+// as DefaultHandler, but ordinary panics are handled as well. See HandlerRecoverAll.
+func DefaultHandlerRecoverAll(reterr *error, elseDefer ...func()) {
+	switch r := recover().(type) {
+	case nil:
+		for _, f := range elseDefer {
+			f()
+		}
+	case handleable:
+		*reterr = r.err
+	default:
+		*reterr = newPanicError(r)
+	}
+}
+
+// newPanicError must be called by the function which recovered panic value r.
+// Stacktrace starts with the function which panicked, stack policy of its package is applied.
+func newPanicError(r interface{}) error {
+	var err error = &PanicError{Value: r}
+	var pcs [64]uintptr
+	n := runtime.Callers(2, pcs[:])
+	for i, pc := range pcs[:n] {
+		if fn := runtime.FuncForPC(frame(pc).pc()); fn != nil && fn.Name() == "runtime.gopanic" {
+			st, depth := callers(i + 2) // skip newPanicError's caller, functions up to gopanic and gopanic itself
+			if len(st) == 0 {
+				return err // it's disabled by the stack policy
+			}
+			return &_errorStack{
+				error: err,
+				stack: st,
+				depth: depth,
+			}
+		}
+	}
+	return err // panic is not found, e.g. recover is called deeper than the deferred function
+}
+
+// Rethrow returns func, which adds context to an error raised by Check...() and raises it again,
+// so that it's handled upper in the call stack. Other panics are not intercepted.
+// Defer the returned func right away:
+//
+//	defer Rethrow(OAnno(text))()
+//
+// Rethrow itself is called at the defer statement to remember the function it's deferred in,
+// so that annotations are printed along with this function in stacktrace.
+func Rethrow(opts ...OptionE) func() {
+	depth := stackDepth(1)
+	return func() {
+		switch r := recover().(type) {
+		case nil:
+		case handleable:
+			panic(handleable{ToSkipE(stackDepth(0)-depth).WrapE(r.err, opts...)})
+		default:
+			panic(r)
+		}
+	}
+}
+
+// AnnotateHandler returns func, which adds context to an error raised by Check...() and puts it in reterr.
+// If no error is raised, but reterr is not nil, context is added to it as well.
+// Other panics are not intercepted.
+// Defer the returned func right away:
+//
+//	defer AnnotateHandler(&reterr, OAnno(text))()
+//
+// See Rethrow for the reason of such usage.
+func AnnotateHandler(reterr *error, opts ...OptionE) func() {
+	depth := stackDepth(1)
+	return func() {
+		switch r := recover().(type) {
+		case nil:
+			*reterr = ToSkipE(stackDepth(0)-depth).WrapE(*reterr, opts...)
+		case handleable:
+			*reterr = ToSkipE(stackDepth(0)-depth).WrapE(r.err, opts...)
+		default:
+			panic(r)
+		}
+	}
+}
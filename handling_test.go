@@ -2,7 +2,11 @@ package errors
 
 import (
 	"database/sql"
+	"fmt"
 	"io"
+	"runtime"
+	"strconv"
+	"strings"
 	"testing"
 )
 
@@ -65,3 +69,262 @@ func TestPanicNil(t *testing.T) {
 	})
 	panic(nil)
 }
+
+func TestGroup(t *testing.T) {
+	var g Group
+	g.Go(func() {})
+	if err := g.Wait(); err != nil {
+		t.Errorf("Wrong result without errors: %v", err)
+	}
+
+	handled := false
+	func() {
+		defer Handler(func(err error) {
+			handled = true
+			if !IsE(err, io.EOF) || !IsE(err, sql.ErrNoRows) {
+				t.Errorf("Wrong error: %v", err)
+			}
+			if StackE(err) == nil {
+				t.Error("Stack of the goroutine is not recorded")
+			}
+		})
+
+		var g Group
+		g.Go(func() { Check(io.EOF) })
+		g.Go(func() { Check(nil) })
+		g.Go(func() { Check(sql.ErrNoRows) })
+		Check(g.Wait())
+		t.Error("can't execute this code")
+	}()
+	if !handled {
+		t.Error("Errors of goroutines are not handled")
+	}
+}
+
+func TestCheckValues(t *testing.T) {
+	twoValues := func(err error) (int, string, error) { return 1, "a", err }
+
+	if v := Check1(strconv.Atoi("1")); v != 1 {
+		t.Errorf("Wrong value: got %v, want %v", v, 1)
+	}
+	if v1, v2 := Check2(twoValues(nil)); v1 != 1 || v2 != "a" {
+		t.Errorf("Wrong values: got %v, %v, want %v, %v", v1, v2, 1, "a")
+	}
+
+	failures := []func(){
+		func() { Check1(strconv.Atoi("a")) },
+		func() { Check2(twoValues(io.EOF)) },
+		func() { Check1(0, io.EOF, OValue("key", "value")) },
+	}
+	for i, f := range failures {
+		handled := false
+		func() {
+			defer Handler(func(err error) {
+				handled = true
+				if s := StackE(err); len(s) == 0 || !strings.HasPrefix(s[0].Function, "github.com/pashaosipyants/errors/v2.TestCheckValues.func") {
+					t.Errorf("%d: wrong stack: %v", i, s)
+				}
+			})
+			f()
+		}()
+		if !handled {
+			t.Errorf("%d: error is not handled", i)
+		}
+	}
+}
+
+func TestRecoverAll(t *testing.T) {
+	var nilMap map[string]int
+
+	failures := []struct {
+		f         func()
+		wantValue interface{}
+	}{
+		{func() { panic("AAA") }, "AAA"},
+		{func() { nilMap["a"] = 1 }, nil},
+		{func() { Check(io.EOF) }, nil},
+	}
+	for i, tt := range failures {
+		var err error
+		func() {
+			defer DefaultHandlerRecoverAll(&err)
+			tt.f()
+		}()
+		if err == nil {
+			t.Fatalf("%d: panic is not handled", i)
+		}
+		if i == len(failures)-1 {
+			if !IsE(err, io.EOF) {
+				t.Errorf("%d: wrong error: %v", i, err)
+			}
+			continue
+		}
+
+		var pe *PanicError
+		if !AsE(err, &pe) {
+			t.Fatalf("%d: error is not *PanicError: %v", i, err)
+		}
+		var re runtime.Error
+		if tt.wantValue != nil && pe.Value != tt.wantValue || tt.wantValue == nil && !AsE(err, &re) {
+			t.Errorf("%d: wrong panic value: %v", i, pe.Value)
+		}
+
+		st := StackE(err)
+		found := false
+		for _, f := range st {
+			if strings.HasPrefix(f.Function, "runtime.") {
+				continue
+			}
+			found = strings.HasPrefix(f.Function, "github.com/pashaosipyants/errors/v2.TestRecoverAll.func")
+			break
+		}
+		if !found {
+			t.Errorf("%d: stack doesn't start at the panic site:\n%s", i, SprintE(err))
+		}
+	}
+
+	handled := false
+	func() {
+		defer HandlerRecoverAll(func(err error) {
+			handled = true
+		})
+		panic("AAA")
+	}()
+	if !handled {
+		t.Error("HandlerRecoverAll haven't handled panic")
+	}
+
+	defer SetStackPolicy(GetStackPolicy())
+	SetStackPolicy(StackPolicy{MaxDepth: 2})
+	var err error
+	func() {
+		defer DefaultHandlerRecoverAll(&err)
+		panic("AAA")
+	}()
+	if st := StackE(err); len(st) != 2 || !strings.HasPrefix(st[0].Function, "github.com/pashaosipyants/errors/v2.TestRecoverAll.func") {
+		t.Errorf("Wrong stack with small MaxDepth:\n%s", SprintE(err))
+	}
+}
+
+type testError struct {
+	code int
+}
+
+func (e *testError) Error() string {
+	return "test error"
+}
+
+func TestMatch(t *testing.T) {
+	var matched string
+	cases := []CaseE{
+		OnIs(io.EOF, func(err error) { matched = "is" }),
+		OnAs(func(err error, te *testError) { matched = fmt.Sprint("as ", te.code) }),
+		OnValue("key", "value", func(err error) { matched = "value" }),
+		OnValue("nil", nil, func(err error) { matched = "nil value" }),
+		OnValue("list", []int{1}, func(err error) { matched = "list" }),
+	}
+
+	tests := []struct {
+		err         error
+		wantMatched string
+	}{
+		{WrapE(io.EOF, OValue("key", "value")), "is"},
+		{WrapE(&testError{code: 1}, OStack()), "as 1"},
+		{WrapE(sql.ErrNoRows, OValue("key", "value")), "value"},
+		{sql.ErrNoRows, ""},
+		{WrapE(sql.ErrNoRows, OValue("nil", nil)), "nil value"},
+		{WrapE(sql.ErrNoRows, OValue("list", []int{1})), ""},
+	}
+	for _, tt := range tests {
+		matched = ""
+		err := MatchE(tt.err, cases...)
+		if matched != tt.wantMatched || (matched == "") != (err != nil) {
+			t.Errorf("Wrong match of %v: got %q, %v, want %q", tt.err, matched, err, tt.wantMatched)
+		}
+	}
+
+	if err := MatchE(sql.ErrNoRows, append(cases, OnDefault(func(err error) { matched = "default" }))...); err != nil || matched != "default" {
+		t.Errorf("Default case is not matched: %v, %q", err, matched)
+	}
+	if MatchE(nil, OnDefault(func(err error) { t.Error("nil is matched") })) != nil {
+		t.Error("nil is not matched")
+	}
+}
+
+func TestHandlerMatch(t *testing.T) {
+	var handled, rethrown bool
+	func() {
+		defer Handler(func(err error) {
+			rethrown = IsE(err, sql.ErrNoRows)
+		})
+		func() {
+			defer HandlerMatch(OnIs(io.EOF, func(err error) { handled = true }))
+			Check(io.EOF)
+		}()
+		func() {
+			defer HandlerMatch(OnIs(io.EOF, func(err error) { t.Error("can't be handled here") }))
+			Check(sql.ErrNoRows)
+		}()
+	}()
+	if !handled || !rethrown {
+		t.Errorf("Wrong handling: handled %v, rethrown %v", handled, rethrown)
+	}
+
+	err := func() (reterr error) {
+		defer DefaultHandlerMatch(&reterr, OnIs(io.EOF, func(err error) {}))
+		Check(sql.ErrNoRows)
+		return nil
+	}()
+	if !IsE(err, sql.ErrNoRows) {
+		t.Errorf("Unmatched error is not returned: %v", err)
+	}
+}
+
+func checkEOF() {
+	Check(io.EOF)
+}
+
+func rethrowing() {
+	defer Rethrow(OAnno("rethrown"), OValue("key", "value"))()
+	checkEOF()
+}
+
+func annotating(err error) (reterr error) {
+	defer AnnotateHandler(&reterr, OAnno("annotated"))()
+	if err != nil {
+		return WrapStackE(err)
+	}
+	checkEOF()
+	return nil
+}
+
+func TestRethrow(t *testing.T) {
+	var err error
+	func() {
+		defer DefaultHandler(&err)
+		rethrowing()
+		t.Error("can't execute this code")
+	}()
+	if !IsE(err, io.EOF) || ValueE(err, "key") != "value" {
+		t.Errorf("Wrong error: %v", err)
+	}
+	if !strings.Contains(SprintE(err), "v2.rethrowing\n\t") ||
+		!strings.Contains(SprintE(err), "\n\tANNOTATION: rethrown\n") ||
+		strings.Contains(SprintE(err), "ELSE ANNOTATIONS") {
+		t.Errorf("Annotation is not printed along with the function Rethrow is deferred in:\n%s", SprintE(err))
+	}
+
+	for _, cause := range []error{nil, sql.ErrNoRows} {
+		err := annotating(cause)
+		sprint := SprintE(err)
+		if err == nil || strings.Contains(sprint, "ELSE ANNOTATIONS") || !strings.Contains(sprint, "\tANNOTATION: annotated\n") {
+			t.Errorf("Wrong annotation of %v:\n%s", cause, sprint)
+		}
+	}
+	if err := func() (reterr error) {
+		defer AnnotateHandler(&reterr, OAnno("annotated"))()
+		return nil
+	}(); err != nil {
+		t.Errorf("nil error is annotated: %v", err)
+	}
+}
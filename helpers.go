@@ -1,5 +1,10 @@
 package errors
 
+import (
+	"context"
+	"sync"
+)
+
 // AnyE is a helper function that returns first not nil error or nil if there are none.
 func AnyE(errs ...error) error {
 	for _, err := range errs {
@@ -20,3 +25,59 @@ func AnyFuncE(errfuncs ...func() error) error {
 	}
 	return nil
 }
+
+// AnyFuncParallelE is a helper function that executes funcs concurrently. As soon as error occurred
+// context passed to funcs is canceled, so that the rest of them can stop their work.
+// It waits for all funcs to finish and returns the first occurred error with errors of the others suppressed.
+// Errors caused by this cancellation (context.Canceled, if ctx itself is not canceled) are not suppressed,
+// they are omitted.
+func AnyFuncParallelE(ctx context.Context, errfuncs ...func(context.Context) error) error {
+	fctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg    sync.WaitGroup
+		mu    sync.Mutex
+		first error
+		rest  []error
+	)
+	for _, f := range errfuncs {
+		wg.Add(1)
+		go func(f func(context.Context) error) {
+			defer wg.Done()
+
+			err := f(fctx)
+			if err == nil {
+				return
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			switch {
+			case first == nil:
+				first = err
+				cancel()
+			case IsE(err, context.Canceled) && ctx.Err() == nil:
+				// caused by the cancellation
+			default:
+				rest = append(rest, err)
+			}
+		}(f)
+	}
+	wg.Wait()
+
+	for i := len(rest) - 1; i >= 0; i-- {
+		first = WrapSuppressedE(first, rest[i])
+	}
+	return first
+}
+
+// AllFuncE is a helper function that executes all funcs one by one, even if some of them return an error.
+// Returns nil if there are no errors, otherwise the first one with the rest of them suppressed. See Collector.Err.
+func AllFuncE(errfuncs ...func() error) error {
+	var c Collector
+	for _, f := range errfuncs {
+		c.Add(f())
+	}
+	return c.Err()
+}
@@ -0,0 +1,234 @@
+/*
+Package httperr translates errors enriched by github.com/pashaosipyants/errors/v2 to http responses.
+
+Status of the response is found by StatusE. Body is problem details of RFC 7807 built from the error's message,
+its code (errors.CodeE) and values exposed with Expose. Error is logged with all the details by Logger.
+
+Middleware handles errors raised by errors.Check...() in http handlers the same way:
+
+	http.Handle("/tasks", httperr.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		task := errors.Check1(LoadTask(r))
+		...
+	})))
+*/
+package httperr
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+
+	"github.com/pashaosipyants/errors/v2"
+)
+
+// StatusKey is the key of http status explicitly added to an error. It has priority over any other mapping.
+var StatusKey = errors.NewKey[int]("httperr.status")
+
+func init() {
+	errors.SetValuePolicy(StatusKey, errors.KeepOutermost)
+	errors.RegisterKey(StatusKey)
+}
+
+// OStatus adds http status to an error. If several statuses are added, the outermost one is used.
+func OStatus(status int) errors.OptionE {
+	return errors.OKeyValue(StatusKey, status)
+}
+
+type sentinelStatus struct {
+	target error
+	status int
+}
+
+type valueStatus struct {
+	key, value interface{}
+	status     int
+}
+
+type exposedValue struct {
+	key    interface{}
+	member string
+}
+
+var mappings struct {
+	sync.RWMutex
+	sentinels []sentinelStatus
+	values    []valueStatus
+	exposed   []exposedValue
+}
+
+// MapSentinel maps errors, which are target in terms of errors.IsPrimaryE, to http status.
+// Suppressed errors are not taken into account, even visible ones.
+// Mappings are checked in order of adding.
+func MapSentinel(target error, status int) {
+	mappings.Lock()
+	defer mappings.Unlock()
+	mappings.sentinels = append(mappings.sentinels, sentinelStatus{target, status})
+}
+
+// MapValue maps errors with value added by key(errors.ValueE(err, key) == value) to http status.
+// Mappings are checked in order of adding.
+func MapValue(key, value interface{}, status int) {
+	mappings.Lock()
+	defer mappings.Unlock()
+	mappings.values = append(mappings.values, valueStatus{key, value, status})
+}
+
+// Expose adds value of an error by key (errors.ValueE(err, key)) to the problem details as member with the given name.
+// Values are not exposed by default, since they may contain sensitive data.
+func Expose(key interface{}, member string) {
+	mappings.Lock()
+	defer mappings.Unlock()
+	mappings.exposed = append(mappings.exposed, exposedValue{key, member})
+}
+
+// StatusE returns http status of err. It's the first found of:
+//  1. status added with OStatus
+//  2. http status of the error code, see errors.CodeE
+//  3. status of the sentinel error err is, see MapSentinel
+//  4. status of the value err has, see MapValue
+//  5. http.StatusInternalServerError
+func StatusE(err error) int {
+	if status, ok := errors.Value(err, StatusKey); ok {
+		return status
+	}
+	if c := errors.CodeE(err); c != nil && c.HTTPStatus() != 0 {
+		return c.HTTPStatus()
+	}
+
+	mappings.RLock()
+	defer mappings.RUnlock()
+	for _, m := range mappings.sentinels {
+		if errors.IsPrimaryE(err, m.target) {
+			return m.status
+		}
+	}
+	for _, m := range mappings.values {
+		if errors.ValueE(err, m.key) == m.value {
+			return m.status
+		}
+	}
+	return http.StatusInternalServerError
+}
+
+// Problem is problem details of RFC 7807.
+type Problem struct {
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+	Status   int    `json:"status"`
+	Detail   string `json:"detail,omitempty"`
+	Instance string `json:"instance,omitempty"`
+	// Code is the name of the error code, see errors.CodeE.
+	Code string `json:"code,omitempty"`
+	// Description is the description of the error code.
+	Description string `json:"description,omitempty"`
+	// Extensions are additional members of the problem details, e.g. values exposed with Expose.
+	Extensions map[string]interface{} `json:"-"`
+}
+
+// MarshalJSON encodes extensions as members of the problem details object along with standard members.
+func (p *Problem) MarshalJSON() ([]byte, error) {
+	type problem Problem // without MarshalJSON
+	std, err := json.Marshal((*problem)(p))
+	if err != nil || len(p.Extensions) == 0 {
+		return std, err
+	}
+
+	members := make(map[string]interface{}, len(p.Extensions))
+	for k, v := range p.Extensions {
+		members[k] = v
+	}
+	if err := json.Unmarshal(std, &members); err != nil { // standard members override extensions
+		return nil, err
+	}
+	return json.Marshal(members)
+}
+
+// NewProblem builds problem details of err for request r.
+// Type is about:blank, so title is the text of the status as RFC 7807 recommends. Detail is the message of err.
+func NewProblem(r *http.Request, err error) *Problem {
+	status := StatusE(err)
+	p := &Problem{
+		Type:   "about:blank",
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: err.Error(),
+	}
+	if r != nil && r.URL != nil {
+		p.Instance = r.URL.RequestURI()
+	}
+	if c := errors.CodeE(err); c != nil {
+		p.Code = c.Name()
+		p.Description = c.Description()
+	}
+
+	mappings.RLock()
+	defer mappings.RUnlock()
+	for _, e := range mappings.exposed {
+		if v := errors.ValueE(err, e.key); v != nil {
+			if p.Extensions == nil {
+				p.Extensions = make(map[string]interface{})
+			}
+			p.Extensions[e.member] = v
+		}
+	}
+	return p
+}
+
+// Logger logs errors written by WriteError. r may be nil.
+// By default it logs errors with all the details (errors.SprintE) by standard log package.
+// Set it in the initialization part of a program.
+var Logger = func(r *http.Request, err error) {
+	if r == nil {
+		log.Printf("%s", errors.SprintE(err))
+		return
+	}
+	log.Printf("%s %s:\n%s", r.Method, r.URL, errors.SprintE(err))
+}
+
+// WriteError logs err with Logger and writes its problem details(see NewProblem) to w. r may be nil.
+// If err is nil nothing is done.
+func WriteError(w http.ResponseWriter, r *http.Request, err error) {
+	if err == nil {
+		return
+	}
+	Logger(r, err)
+
+	p := NewProblem(r, err)
+	body, errMarshal := json.Marshal(p)
+	if errMarshal != nil {
+		Logger(r, errors.WrapE(errMarshal, errors.OStack(), errors.OAnno(fmt.Sprintf("problem details of %q", err))))
+		http.Error(w, http.StatusText(p.Status), p.Status)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(p.Status)
+	_, _ = w.Write(body)
+}
+
+// Middleware handles errors raised by errors.Check...() in next with WriteError.
+// Other panics are not intercepted.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer errors.Handler(func(err error) {
+			WriteError(w, r, err)
+		})
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// HandlerFunc is an http handler, which returns an error.
+// Returned error, as well as raised by errors.Check...(), is written with WriteError.
+type HandlerFunc func(w http.ResponseWriter, r *http.Request) error
+
+func (f HandlerFunc) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	defer errors.Handler(func(err error) {
+		WriteError(w, r, err)
+	})
+
+	WriteError(w, r, f(w, r))
+}
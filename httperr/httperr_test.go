@@ -0,0 +1,125 @@
+package httperr
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/pashaosipyants/errors/v2"
+)
+
+var codeTestNotFound = errors.RegisterCode("httperr_test_not_found", 404001, "resource is not found", http.StatusNotFound)
+
+func init() {
+	MapSentinel(sql.ErrNoRows, http.StatusNotFound)
+	MapValue("api", "login", http.StatusUnauthorized)
+	Expose("user", "user")
+}
+
+func TestStatusE(t *testing.T) {
+	tests := []struct {
+		err  error
+		want int
+	}{
+		{io.EOF, http.StatusInternalServerError},
+		{errors.WrapE(sql.ErrNoRows, errors.OStack()), http.StatusNotFound},
+		{errors.WrapE(io.EOF, errors.OValue("api", "login")), http.StatusUnauthorized},
+		{errors.WrapE(io.EOF, errors.OCode(codeTestNotFound)), http.StatusNotFound},
+		{errors.WrapE(sql.ErrNoRows, OStatus(http.StatusGone)), http.StatusGone},
+		{errors.WrapE(io.EOF, OStatus(http.StatusGone), OStatus(http.StatusTeapot)), http.StatusTeapot},
+		{errors.WrapE(io.EOF, errors.OSuppVisible(sql.ErrNoRows)), http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		if got := StatusE(tt.err); got != tt.want {
+			t.Errorf("Wrong status of %v: got %d, want %d", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	var logged string
+	defer func(l func(*http.Request, error)) { Logger = l }(Logger)
+	Logger = func(r *http.Request, err error) {
+		logged = errors.SprintE(err)
+	}
+
+	handler := Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		errors.Check(io.EOF, errors.OCode(codeTestNotFound), errors.OValue("user", 239), errors.OValue("secret", "a"))
+	}))
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/tasks/1?a=b", nil))
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Wrong status: got %d, want %d", w.Code, http.StatusNotFound)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "application/problem+json" {
+		t.Errorf("Wrong content type: %q", ct)
+	}
+	var got map[string]interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+		t.Fatalf("Wrong body %q: %v", w.Body.String(), err)
+	}
+	want := map[string]interface{}{
+		"type":        "about:blank",
+		"title":       "Not Found",
+		"status":      404.0,
+		"detail":      "EOF",
+		"instance":    "/tasks/1?a=b",
+		"code":        "httperr_test_not_found",
+		"description": "resource is not found",
+		"user":        239.0,
+	}
+	if len(got) != len(want) {
+		t.Errorf("Wrong body: got %v, want %v", got, want)
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("Wrong %q: got %v, want %v", k, got[k], v)
+		}
+	}
+	if !strings.Contains(logged, "STACK:") {
+		t.Errorf("Error is not logged with details: %q", logged)
+	}
+}
+
+func TestWriteErrorWithoutRequest(t *testing.T) {
+	var logged bytes.Buffer
+	defer log.SetOutput(log.Writer())
+	log.SetOutput(&logged)
+
+	w := httptest.NewRecorder()
+	WriteError(w, nil, sql.ErrNoRows)
+	if w.Code != http.StatusNotFound || !strings.Contains(logged.String(), "ERROR:") {
+		t.Errorf("Wrong response without request: %d, logged %q", w.Code, logged.String())
+	}
+}
+
+func TestHandlerFunc(t *testing.T) {
+	defer func(l func(*http.Request, error)) { Logger = l }(Logger)
+	Logger = func(*http.Request, error) {}
+
+	tests := []struct {
+		f    HandlerFunc
+		want int
+	}{
+		{func(w http.ResponseWriter, r *http.Request) error { return nil }, http.StatusOK},
+		{func(w http.ResponseWriter, r *http.Request) error { return sql.ErrNoRows }, http.StatusNotFound},
+		{func(w http.ResponseWriter, r *http.Request) error {
+			errors.Check(io.EOF)
+			return nil
+		}, http.StatusInternalServerError},
+	}
+	for i, tt := range tests {
+		w := httptest.NewRecorder()
+		tt.f.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+		if w.Code != tt.want {
+			t.Errorf("%d: wrong status: got %d, want %d", i, w.Code, tt.want)
+		}
+	}
+}
@@ -0,0 +1,314 @@
+package errors
+
+/*
+	encode an error with all the details to json and decode it back
+*/
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+)
+
+// EncodeJSON returns json representation of err with all the details.
+// With error msg, stacktrace, annotations, values and suppressed errors.
+// Values whose key or value can't be encoded to json are omitted.
+// Empty fields are omitted as well, except message.
+// Names of sentinel errors registered with RegisterSentinelE, which err is, are saved to restore them by DecodeE.
+//
+// Schema:
+//
+//	{
+//	  "message": "error message",
+//	  "sentinels": ["pkg.ErrSmth"],
+//	  "stack": [
+//	    {"function": "github.com/user/pkg.Func", "file": "/path/file.go", "line": 42, "package": "github.com/user/pkg", "remote": true}
+//	  ],
+//	  "annotations": [
+//	    {"annotation": "additional msg", "where": "github.com/user/pkg.Func", "file": "/path/file.go", "line": 43, "frame": 0}
+//	  ],
+//	  "values": [
+//	    {"key": "key", "value": "value"},
+//	    {"key": "list", "value": ["value1", "value2"], "appended": true}
+//	  ],
+//	  "suppressed": [
+//	    {"message": "suppressed error message", "visible": true, ...same schema recursively}
+//	  ],
+//	  "joined": [
+//	    {"message": "joined error message", ...same schema recursively}
+//	  ]
+//	}
+//
+// Stack starts with the deepest frame. "remote" is present only for frames of an error restored by DecodeE.
+// "visible" is present only for suppressed errors added with WrapSuppressedVisibleE.
+// "appended" is present only for values accumulated by AppendValues policy.
+// "joined" are errors wrapped by Unwrap() []error (e.g. created by errors.Join), other fields contain details
+// of the error itself and errors wrapped by Unwrap() error only, details of joined errors are in their objects.
+// "frame" of an annotation is index of the stack frame annotation was added at, it's absent if there is no such frame.
+// Annotations and values are listed in the chain order, starting with the outermost one.
+func EncodeJSON(err error) ([]byte, error) {
+	return json.Marshal(newJSONError(err))
+}
+
+type jsonError struct {
+	Message     string           `json:"message"`
+	Sentinels   []string         `json:"sentinels,omitempty"`
+	Stack       StackTrace       `json:"stack,omitempty"`
+	Annotations []jsonAnnotation `json:"annotations,omitempty"`
+	Values      []jsonValue      `json:"values,omitempty"`
+	Suppressed  []*jsonError     `json:"suppressed,omitempty"`
+	Visible     bool             `json:"visible,omitempty"`
+	Joined      []*jsonError     `json:"joined,omitempty"`
+}
+
+type jsonAnnotation struct {
+	Annotation string `json:"annotation"`
+	Where      string `json:"where"`
+	File       string `json:"file"`
+	Line       int    `json:"line"`
+	Frame      *int   `json:"frame,omitempty"`
+}
+
+type jsonValue struct {
+	Key      json.RawMessage `json:"key"`
+	Value    json.RawMessage `json:"value"`
+	Appended bool            `json:"appended,omitempty"`
+}
+
+func newJSONError(err error) *jsonError {
+	if err == nil {
+		return nil
+	}
+
+	chain, branches := chainE(err)
+	je := &jsonError{
+		Message:   err.Error(),
+		Sentinels: sentinelNames(err),
+	}
+
+	var as annotatedStack
+	if errStack := stackOf(chain); errStack != nil {
+		je.Stack = errStack.trace()
+		as.stack = je.Stack
+		as.depth = errStack.depth
+	}
+
+	for _, a := range annotationsOf(chain) {
+		ja := jsonAnnotation{
+			Annotation: a.annotation,
+			Where:      a.where,
+			File:       a.file,
+			Line:       a.line,
+		}
+		if i := as.frameOf(a); i >= 0 {
+			ja.Frame = &i
+		}
+		je.Annotations = append(je.Annotations, ja)
+	}
+
+	for _, e := range chain {
+		switch x := e.(type) {
+		case *_errorValue:
+			key, errKey := json.Marshal(x.key)
+			value, errValue := json.Marshal(x.value)
+			if errKey != nil || errValue != nil {
+				continue
+			}
+			je.Values = append(je.Values, jsonValue{
+				Key:      key,
+				Value:    value,
+				Appended: x.appended,
+			})
+		case *_errorSuppressed:
+			if js := newJSONError(x.suppressed); js != nil {
+				js.Visible = x.visible
+				je.Suppressed = append(je.Suppressed, js)
+			}
+		}
+	}
+
+	for _, br := range branches {
+		if jb := newJSONError(br); jb != nil {
+			je.Joined = append(je.Joined, jb)
+		}
+	}
+
+	return je
+}
+
+// DecodeE restores an error encoded by EncodeJSON (or json.Marshal) from data.
+// Restored error has the same message, annotations, values and suppressed errors.
+// Its stacktrace is marked as remote, see StackE. SprintE prints it as REMOTE STACK.
+// Keys and values are restored as encoding/json decodes them to interface{}, e.g. numbers become float64,
+// except keys registered with RegisterKey, which are restored along with the types of their values.
+// decoded is nil if data is json null.
+//
+// Restored error is every sentinel error(in terms of IsE) the original one was, if this sentinel is registered
+// with RegisterSentinelE with the same name on both sides.
+func DecodeE(data []byte) (decoded error, err error) {
+	var je *jsonError
+	if err = json.Unmarshal(data, &je); err != nil {
+		return nil, err
+	}
+	return je.decode(), nil
+}
+
+// decode restores key and value. Keys registered with RegisterKey are restored along with the types of their values.
+func (jv jsonValue) decode() (key, value interface{}, ok bool) {
+	var name string
+	if json.Unmarshal(jv.Key, &name) == nil {
+		if k, found := keyByName(name); found {
+			if value, err := k.decodeValue(jv.Value, jv.Appended); err == nil {
+				return k, value, true
+			}
+		}
+	}
+	if json.Unmarshal(jv.Key, &key) != nil || json.Unmarshal(jv.Value, &value) != nil {
+		return nil, nil, false
+	}
+	return key, value, true
+}
+
+func (je *jsonError) decode() error {
+	if je == nil {
+		return nil
+	}
+
+	remote := &_errorRemote{
+		msg:       je.Message,
+		sentinels: sentinelsByNames(je.Sentinels),
+	}
+	for _, jb := range je.Joined {
+		remote.joined = append(remote.joined, jb.decode())
+	}
+	var err error = remote
+
+	for i := len(je.Values) - 1; i >= 0; i-- {
+		key, value, ok := je.Values[i].decode()
+		if !ok {
+			continue
+		}
+		_, isList := value.([]interface{})
+		err = &_errorValue{
+			error:    err,
+			key:      key,
+			value:    value,
+			appended: je.Values[i].Appended && isList,
+		}
+	}
+
+	for i := len(je.Annotations) - 1; i >= 0; i-- {
+		ja := je.Annotations[i]
+		a := &_errorAnnotation{
+			error:      err,
+			where:      ja.Where,
+			annotation: ja.Annotation,
+			file:       ja.File,
+			line:       ja.Line,
+		}
+		if ja.Frame != nil {
+			a.depth = len(je.Stack) - *ja.Frame // the same frame in the remote stack, see annotatedStack.frameOf
+		} else {
+			a.depth = -1 // known to be out of the remote stack
+		}
+		err = a
+	}
+
+	if len(je.Stack) != 0 {
+		remote := make(StackTrace, len(je.Stack))
+		for i, f := range je.Stack {
+			f.Remote = true
+			remote[i] = f
+		}
+		err = &_errorStack{
+			error:  err,
+			remote: remote,
+			depth:  len(remote),
+		}
+	}
+
+	for i := len(je.Suppressed) - 1; i >= 0; i-- {
+		err = &_errorSuppressed{
+			error:      err,
+			suppressed: je.Suppressed[i].decode(),
+			visible:    je.Suppressed[i].Visible,
+		}
+	}
+
+	return err
+}
+
+// _errorRemote is the deepest error of the chain restored by DecodeE.
+type _errorRemote struct {
+	msg       string
+	sentinels []error
+	joined    []error
+}
+
+func (e *_errorRemote) Error() string {
+	return e.msg
+}
+
+func (e *_errorRemote) Unwrap() []error {
+	return e.joined
+}
+
+func (e *_errorRemote) Is(target error) bool {
+	for _, s := range e.sentinels {
+		if s == target {
+			return true
+		}
+	}
+	return false
+}
+
+var sentinels = struct {
+	sync.RWMutex
+	names  []string
+	byName map[string]error
+}{
+	byName: make(map[string]error),
+}
+
+// RegisterSentinelE registers sentinel error with the given name, so that it survives EncodeJSON/DecodeE round trip.
+// I.e. IsE(decoded, sentinel) is true if IsE(original, sentinel) is true and
+// sentinel is registered with the same name on both encoding and decoding sides.
+// Panics if the name is already registered for another error.
+// Usually it's called in the initialization part of a program.
+func RegisterSentinelE(name string, sentinel error) {
+	sentinels.Lock()
+	defer sentinels.Unlock()
+
+	if registered, ok := sentinels.byName[name]; ok {
+		if registered != sentinel {
+			panic(fmt.Sprintf("errors: sentinel %q is already registered", name))
+		}
+		return
+	}
+	sentinels.names = append(sentinels.names, name)
+	sentinels.byName[name] = sentinel
+}
+
+func sentinelNames(err error) (names []string) {
+	sentinels.RLock()
+	defer sentinels.RUnlock()
+
+	for _, name := range sentinels.names {
+		if IsPrimaryE(err, sentinels.byName[name]) {
+			names = append(names, name)
+		}
+	}
+	return
+}
+
+func sentinelsByNames(names []string) (errs []error) {
+	sentinels.RLock()
+	defer sentinels.RUnlock()
+
+	for _, name := range names {
+		if s, ok := sentinels.byName[name]; ok {
+			errs = append(errs, s)
+		}
+	}
+	return
+}
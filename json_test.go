@@ -0,0 +1,157 @@
+package errors
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestEncodeJSON(t *testing.T) {
+	err := WrapE(io.EOF,
+		OStack(),
+		OAnno("anno"),
+		OValue("a", "b"),
+		OValue("func", func() {}), // can't be encoded
+		OSupp(WrapE(sql.ErrNoRows, OValue(1, 2))),
+	)
+
+	data, errEnc := EncodeJSON(err)
+	if errEnc != nil {
+		t.Fatalf("EncodeJSON failed: %v", errEnc)
+	}
+	if marshaled, _ := json.Marshal(err); string(marshaled) != string(data) {
+		t.Errorf("json.Marshal and EncodeJSON differ: %s != %s", marshaled, data)
+	}
+
+	var got struct {
+		Message     string
+		Stack       []Frame
+		Annotations []struct{ Annotation, Where string }
+		Values      []struct{ Key, Value interface{} }
+		Suppressed  []struct {
+			Message string
+			Stack   []Frame
+			Values  []struct{ Key, Value interface{} }
+		}
+	}
+	if errDec := json.Unmarshal(data, &got); errDec != nil {
+		t.Fatalf("Unmarshal failed: %v", errDec)
+	}
+
+	if got.Message != "EOF" {
+		t.Errorf("Wrong message: got %q, want %q", got.Message, "EOF")
+	}
+	if len(got.Stack) == 0 || got.Stack[0].Function != "github.com/pashaosipyants/errors/v2.TestEncodeJSON" {
+		t.Errorf("Wrong stack: %v", got.Stack)
+	}
+	if len(got.Annotations) != 1 || got.Annotations[0].Annotation != "anno" ||
+		got.Annotations[0].Where != "github.com/pashaosipyants/errors/v2.TestEncodeJSON" {
+		t.Errorf("Wrong annotations: %v", got.Annotations)
+	}
+	if len(got.Values) != 1 || got.Values[0].Key != "a" || got.Values[0].Value != "b" {
+		t.Errorf("Wrong values: %v", got.Values)
+	}
+	if len(got.Suppressed) != 1 {
+		t.Fatalf("Wrong number of suppressed: got %d, want 1", len(got.Suppressed))
+	}
+	s := got.Suppressed[0]
+	if s.Message != sql.ErrNoRows.Error() || s.Stack != nil ||
+		len(s.Values) != 1 || s.Values[0].Key != 1.0 || s.Values[0].Value != 2.0 {
+		t.Errorf("Wrong suppressed: %+v", s)
+	}
+}
+
+func TestDecodeE(t *testing.T) {
+	RegisterSentinelE("io.EOF", io.EOF)
+	RegisterSentinelE("io.EOF", io.EOF) // the same registration is allowed
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("Duplicate registration haven't panicked")
+			}
+		}()
+		RegisterSentinelE("io.EOF", io.ErrUnexpectedEOF)
+	}()
+
+	orig := WrapE(io.EOF,
+		OStack(),
+		OAnno("anno"),
+		OValue("a", "b"),
+		OSupp(WrapE(sql.ErrNoRows, OStack())),
+	)
+	data, err := EncodeJSON(orig)
+	if err != nil {
+		t.Fatalf("EncodeJSON failed: %v", err)
+	}
+	decoded, err := DecodeE(data)
+	if err != nil {
+		t.Fatalf("DecodeE failed: %v", err)
+	}
+
+	if decoded.Error() != orig.Error() {
+		t.Errorf("Wrong message: got %q, want %q", decoded.Error(), orig.Error())
+	}
+	if !IsE(decoded, io.EOF) {
+		t.Error("Decoded error is not registered sentinel")
+	}
+	if ValueE(decoded, "a") != "b" {
+		t.Errorf("Wrong value: got %v, want %v", ValueE(decoded, "a"), "b")
+	}
+	s := SuppressedE(decoded)
+	if len(s) != 1 || s[0].Error() != sql.ErrNoRows.Error() || IsE(s[0], sql.ErrNoRows) {
+		t.Errorf("Wrong suppressed: %v", s)
+	}
+
+	origStack, decodedStack := StackE(orig), StackE(decoded)
+	if len(origStack) != len(decodedStack) {
+		t.Fatalf("Wrong stack length: got %d, want %d", len(decodedStack), len(origStack))
+	}
+	for i := range origStack {
+		want := origStack[i]
+		want.Remote = true
+		if decodedStack[i] != want {
+			t.Errorf("Wrong frame %d: got %v, want %v", i, decodedStack[i], want)
+		}
+	}
+
+	sprint := SprintE(decoded)
+	if !strings.Contains(sprint, "REMOTE STACK:") || !strings.Contains(sprint, "ANNOTATION: anno") {
+		t.Errorf("Wrong SprintE of decoded error:\n%s", sprint)
+	}
+
+	if reencoded, _ := EncodeJSON(decoded); len(reencoded) == 0 {
+		t.Error("Decoded error can't be encoded")
+	}
+	if decoded, err := DecodeE([]byte("null")); decoded != nil || err != nil {
+		t.Errorf("Wrong decoding of null: got %v, %v", decoded, err)
+	}
+}
+
+func TestDecodeAppendedValues(t *testing.T) {
+	orig := WrapE(io.EOF, OValuePolicy("list", 1, AppendValues), OValuePolicy("list", 2, AppendValues))
+	decoded := mustDecodeE(t, mustEncodeJSON(t, orig))
+
+	err := WrapValuePolicyE(decoded, "list", 3, AppendValues)
+	if got := fmt.Sprint(ValueE(err, "list")); got != "[1 2 3]" {
+		t.Errorf("Wrong appended value after decoding: %s", got)
+	}
+}
+
+func mustEncodeJSON(t *testing.T, err error) []byte {
+	data, errEnc := EncodeJSON(err)
+	if errEnc != nil {
+		t.Fatalf("EncodeJSON failed: %v", errEnc)
+	}
+	return data
+}
+
+func mustDecodeE(t *testing.T, data []byte) error {
+	decoded, err := DecodeE(data)
+	if err != nil {
+		t.Fatalf("DecodeE failed: %v", err)
+	}
+	return decoded
+}
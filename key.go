@@ -0,0 +1,129 @@
+package errors
+
+/*
+	typed keys of values added to an error
+*/
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+)
+
+// Key is a typed key of a value added to an error.
+// Values added with different keys never collide, even if keys have the same name.
+//
+// E.g.:
+//
+//	var LoggerKey = NewKey[*logrus.Entry]("logger")
+//	...
+//	err = WithValue(err, LoggerKey, l)
+//	...
+//	if l, ok := Value(err, LoggerKey); ok {
+//		l.Error(SprintE(err))
+//	}
+type Key[T any] struct {
+	name   string
+	decode func(data []byte) (T, error) // restores value encoded to json, json.Unmarshal is used if nil
+}
+
+// NewKey creates new key. name is used only to print or encode the key, it doesn't identify the key.
+func NewKey[T any](name string) *Key[T] {
+	return &Key[T]{name: name}
+}
+
+func (k *Key[T]) String() string {
+	return k.name
+}
+
+// MarshalJSON encodes the key as its name, so that EncodeJSON saves values added with the key.
+// Register the key with RegisterKey to restore it by DecodeE.
+func (k *Key[T]) MarshalJSON() ([]byte, error) {
+	return json.Marshal(k.name)
+}
+
+// decodeValue restores value added with the key from its json representation.
+// If appended is true, data is a list of values accumulated according to AppendValues policy.
+func (k *Key[T]) decodeValue(data []byte, appended bool) (interface{}, error) {
+	if appended {
+		var raws []json.RawMessage
+		if err := json.Unmarshal(data, &raws); err != nil {
+			return nil, err
+		}
+		values := make([]interface{}, 0, len(raws))
+		for _, raw := range raws {
+			v, err := k.decodeValue(raw, false)
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, v)
+		}
+		return values, nil
+	}
+
+	if k.decode != nil {
+		return k.decode(data)
+	}
+	var v T
+	err := json.Unmarshal(data, &v)
+	return v, err
+}
+
+type registeredKey interface {
+	decodeValue(data []byte, appended bool) (interface{}, error)
+}
+
+var keys = struct {
+	sync.RWMutex
+	byName map[string]registeredKey
+}{
+	byName: make(map[string]registeredKey),
+}
+
+// RegisterKey registers key by its name, so that it survives EncodeJSON/DecodeE round trip.
+// I.e. values added with the key are restored by the key itself and with type T, so Value(decoded, key) finds them,
+// if the key is registered with the same name on both encoding and decoding sides.
+// Panics if the name is already registered for another key.
+// Usually it's called in the initialization part of a program.
+func RegisterKey[T any](key *Key[T]) {
+	keys.Lock()
+	defer keys.Unlock()
+
+	if registered, ok := keys.byName[key.name]; ok {
+		if registered != registeredKey(key) {
+			panic(fmt.Sprintf("errors: key %q is already registered", key.name))
+		}
+		return
+	}
+	keys.byName[key.name] = key
+}
+
+func keyByName(name string) (registeredKey, bool) {
+	keys.RLock()
+	defer keys.RUnlock()
+	k, ok := keys.byName[name]
+	return k, ok
+}
+
+// WithValue is the typed version of WrapValueE.
+func WithValue[T any](err error, key *Key[T], value T) error {
+	return WrapValueE(err, key, value)
+}
+
+// Value is the typed version of ValueE. found reports whether the value is found.
+// found is false if the value is not of type T, e.g. if it's accumulated according to AppendValues policy.
+func Value[T any](err error, key *Key[T]) (value T, found bool) {
+	v, found := lookupValue(err, key)
+	if !found || v == nil {
+		return value, found
+	}
+	value, found = v.(T)
+	return value, found
+}
+
+// OKeyValue is the typed version of OValue.
+func OKeyValue[T any](key *Key[T], value T) OptionE {
+	return func(err error, _ int) error {
+		return WithValue(err, key, value)
+	}
+}
@@ -0,0 +1,117 @@
+/*
+Package logrushook expands errors enriched by github.com/pashaosipyants/errors/v2 into structured logrus fields.
+
+When an entry has an error field (logrus.ErrorKey), Hook adds stacktrace, annotations, suppressed errors
+and values of the error as separate fields, so that they are not lost by formatters printing error.Error() only:
+
+	logger.AddHook(&logrushook.Hook{LoggerKey: LoggerKey})
+	...
+	logger.WithError(err).Error("task is not created")
+
+If the error holds a logger added by LoggerKey, fields of that logger are added too.
+*/
+package logrushook
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/pashaosipyants/errors/v2"
+)
+
+// Names of the fields added by Hook.
+const (
+	FieldStack       = "stack"
+	FieldAnnotations = "annotations"
+	FieldSuppressed  = "suppressed"
+)
+
+// Hook is a logrus.Hook which expands errors into fields. Zero value is ready to use.
+type Hook struct {
+	// LoggerKey is the key of *logrus.Entry added to errors, e.g. with errors.OKeyValue.
+	// Fields of the found logger are added to the entry, unless the entry already has fields with the same names.
+	// If nil, loggers are not looked for.
+	LoggerKey interface{}
+	// Prefix is prepended to the names of all added fields, e.g. "error." gives "error.stack".
+	Prefix string
+	// LogLevels are levels the hook is fired on. All levels if empty.
+	LogLevels []logrus.Level
+}
+
+// Levels implements logrus.Hook.
+func (h *Hook) Levels() []logrus.Level {
+	if len(h.LogLevels) == 0 {
+		return logrus.AllLevels
+	}
+	return h.LogLevels
+}
+
+// Fire implements logrus.Hook. Entries without an error field are left as is.
+//
+// Values are added as fields named by fmt.Sprint(key), shadowed values and loggers are omitted.
+// Values which can't be encoded to json are added as fmt.Sprint(value).
+// Fields already present in the entry are never overwritten.
+func (h *Hook) Fire(entry *logrus.Entry) error {
+	err, ok := entry.Data[logrus.ErrorKey].(error)
+	if !ok || err == nil {
+		return nil
+	}
+
+	fields := make(logrus.Fields)
+	if st := errors.StackE(err); st != nil {
+		stack := make([]string, 0, len(st))
+		for _, f := range st {
+			stack = append(stack, fmt.Sprintf("%s %s:%d", f.Function, f.File, f.Line))
+		}
+		fields[h.Prefix+FieldStack] = stack
+	}
+	if annos := errors.AnnotationsE(err); len(annos) > 0 {
+		messages := make([]string, 0, len(annos))
+		for _, a := range annos {
+			messages = append(messages, fmt.Sprintf("%s:%d: %s", a.File, a.Line, a.Message))
+		}
+		fields[h.Prefix+FieldAnnotations] = messages
+	}
+	if supps := errors.SuppressedE(err); len(supps) > 0 {
+		messages := make([]string, 0, len(supps))
+		for _, s := range supps {
+			messages = append(messages, fmt.Sprint(s))
+		}
+		fields[h.Prefix+FieldSuppressed] = messages
+	}
+	for _, kv := range errors.ValuesE(err) {
+		if _, isLogger := kv.Value.(*logrus.Entry); kv.Shadowed || isLogger {
+			continue
+		}
+		value := kv.Value
+		if _, errJSON := json.Marshal(value); errJSON != nil {
+			value = fmt.Sprint(value) // otherwise logrus.JSONFormatter drops the whole entry
+		}
+		fields[h.Prefix+fmt.Sprint(kv.Key)] = value
+	}
+	if h.LoggerKey != nil {
+		if l, ok := errors.ValueE(err, h.LoggerKey).(*logrus.Entry); ok && l != nil {
+			for k, v := range l.Data {
+				if _, ok := fields[k]; !ok {
+					fields[k] = v
+				}
+			}
+		}
+	}
+	if len(fields) == 0 {
+		return nil
+	}
+
+	// entry.Data is shared with the entry the log method was called on, so it must not be modified in place
+	data := make(logrus.Fields, len(entry.Data)+len(fields))
+	for k, v := range fields {
+		data[k] = v
+	}
+	for k, v := range entry.Data {
+		data[k] = v
+	}
+	entry.Data = data
+	return nil
+}
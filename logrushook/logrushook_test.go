@@ -0,0 +1,118 @@
+package logrushook
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/pashaosipyants/errors/v2"
+)
+
+var loggerKey = errors.NewKey[*logrus.Entry]("logrushook_test.logger")
+
+func newLogger(h *Hook) (*logrus.Logger, *bytes.Buffer) {
+	buf := &bytes.Buffer{}
+	l := logrus.New()
+	l.SetOutput(buf)
+	l.SetFormatter(&logrus.JSONFormatter{})
+	l.AddHook(h)
+	return l, buf
+}
+
+func decodeEntry(t *testing.T, buf *bytes.Buffer) map[string]interface{} {
+	t.Helper()
+	var m map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &m); err != nil {
+		t.Fatalf("Can't decode entry %s: %v", buf, err)
+	}
+	buf.Reset()
+	return m
+}
+
+func TestHook(t *testing.T) {
+	l, buf := newLogger(&Hook{LoggerKey: loggerKey})
+
+	errLogger := l.WithField("service", "database").WithField("user", "from logger")
+	err := errors.WrapE(io.EOF, errors.OStack(), errors.OAnno("saving task"), errors.OValue("user", "john"),
+		errors.OKeyValue(loggerKey, errLogger), errors.OSupp(io.ErrUnexpectedEOF))
+	err = errors.WrapE(err, errors.OValue("user", "bob"))
+
+	entry := l.WithField("application", "tasks").WithError(err)
+	entry.Error("failed")
+	m := decodeEntry(t, buf)
+
+	if m["error"] != "EOF" || m["application"] != "tasks" || m["msg"] != "failed" {
+		t.Errorf("Wrong common fields: %v", m)
+	}
+	if st, _ := m[FieldStack].([]interface{}); len(st) == 0 {
+		t.Errorf("Stack is not added: %v", m)
+	}
+	if annos, _ := m[FieldAnnotations].([]interface{}); len(annos) != 1 || !bytes.HasSuffix([]byte(annos[0].(string)), []byte(": saving task")) {
+		t.Errorf("Wrong annotations: %v", m[FieldAnnotations])
+	}
+	if supps, _ := m[FieldSuppressed].([]interface{}); len(supps) != 1 || supps[0] != io.ErrUnexpectedEOF.Error() {
+		t.Errorf("Wrong suppressed: %v", m[FieldSuppressed])
+	}
+	if m["user"] != errors.ValueE(err, "user") {
+		t.Errorf("Wrong value of the error: %v", m["user"])
+	}
+	if m["service"] != "database" {
+		t.Errorf("Fields of the logger from the error are not added: %v", m)
+	}
+	if _, ok := m[loggerKey.String()]; ok {
+		t.Errorf("Logger is added as value: %v", m)
+	}
+	if len(entry.Data) != 2 {
+		t.Errorf("Data of the original entry is modified: %v", entry.Data)
+	}
+
+	l.Info("no error")
+	if m := decodeEntry(t, buf); len(m) != 3 {
+		t.Errorf("Entry without error is modified: %v", m)
+	}
+
+	l.WithError(io.EOF).Warn("plain error")
+	if m := decodeEntry(t, buf); len(m) != 4 {
+		t.Errorf("Entry with plain error is modified: %v", m)
+	}
+}
+
+func TestHookNotEncodableValue(t *testing.T) {
+	l, buf := newLogger(&Hook{})
+
+	err := errors.WrapE(io.EOF, errors.OStack(), errors.OValue("cb", func() {}), errors.OValue("a", 1))
+	l.WithError(err).Error("failed")
+	m := decodeEntry(t, buf)
+	if cb, ok := m["cb"].(string); !ok || cb == "" {
+		t.Errorf("Func value is not added as string: %v", m)
+	}
+	if m["a"] != 1.0 {
+		t.Errorf("Wrong value: %v", m["a"])
+	}
+}
+
+func TestHookOptions(t *testing.T) {
+	l, buf := newLogger(&Hook{Prefix: "error.", LogLevels: []logrus.Level{logrus.ErrorLevel}})
+
+	err := errors.WrapE(io.EOF, errors.OStack(), errors.OKeyValue(loggerKey, l.WithField("service", "database")))
+
+	l.WithError(err).Error("failed")
+	m := decodeEntry(t, buf)
+	if _, ok := m["error."+FieldStack]; !ok {
+		t.Errorf("Prefix is not used: %v", m)
+	}
+	if _, ok := m["service"]; ok {
+		t.Errorf("Logger is used without LoggerKey: %v", m)
+	}
+	if _, ok := m["error."+loggerKey.String()]; ok {
+		t.Errorf("Logger is added as value: %v", m)
+	}
+
+	l.WithError(err).Warn("failed")
+	if m := decodeEntry(t, buf); len(m) != 4 {
+		t.Errorf("Hook is fired on the wrong level: %v", m)
+	}
+}
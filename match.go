@@ -0,0 +1,99 @@
+package errors
+
+/*
+	declarative handling of an error by cases
+*/
+
+// CaseE is a case of error handling for MatchE and HandlerMatch.
+// It handles err and returns true if err matches the case, otherwise returns false and doesn't do anything.
+type CaseE func(err error) bool
+
+// OnIs matches err if IsE(err, target).
+func OnIs(target error, handle func(err error)) CaseE {
+	return func(err error) bool {
+		if !IsE(err, target) {
+			return false
+		}
+		handle(err)
+		return true
+	}
+}
+
+// OnAs matches err if AsE(err, &target) for target of type T. E.g. OnAs(func(err error, ue *UserError) {...}).
+// T must be an error type, so that a wrong target is caught at compile time rather than by a panic in AsE.
+func OnAs[T error](handle func(err error, target T)) CaseE {
+	return func(err error) bool {
+		var target T
+		if !AsE(err, &target) {
+			return false
+		}
+		handle(err, target)
+		return true
+	}
+}
+
+// OnValue matches err if it has value for the key and ValueE(err, key) == value.
+// Not comparable values, e.g. accumulated according to AppendValues policy, never match.
+func OnValue(key, value interface{}, handle func(err error)) CaseE {
+	return func(err error) bool {
+		if v, found := lookupValue(err, key); !found || !isComparable(v) || v != value {
+			return false
+		}
+		handle(err)
+		return true
+	}
+}
+
+// OnDefault matches any error. Put it last.
+func OnDefault(handle func(err error)) CaseE {
+	return func(err error) bool {
+		handle(err)
+		return true
+	}
+}
+
+// MatchE handles err with the first matching case.
+// Returns err if no case matched, otherwise returns nil. If err is nil returns nil, cases are not checked.
+func MatchE(err error, cases ...CaseE) error {
+	if err == nil {
+		return nil
+	}
+	for _, c := range cases {
+		if c(err) {
+			return nil
+		}
+	}
+	return err
+}
+
+// as Handler, but error is handled with the first matching case.
+// If no case matched, error is raised again, so that it can be handled by Handler upper in the call stack.
+// E.g.:
+//
+//	defer HandlerMatch(
+//		OnIs(sql.ErrNoRows, func(err error) { ... }),
+//		OnAs(func(err error, ue *UserError) { ... }),
+//		OnValue("api", "create_task", func(err error) { ... }),
+//	)
+func HandlerMatch(cases ...CaseE) {
+	switch r := recover().(type) {
+	case nil:
+	case handleable:
+		if err := MatchE(r.err, cases...); err != nil {
+			panic(handleable{err})
+		}
+	default:
+		panic(r)
+	}
+}
+
+// as HandlerMatch, but instead of raising unmatched error put it in reterr.
+func DefaultHandlerMatch(reterr *error, cases ...CaseE) {
+	switch r := recover().(type) {
+	case nil:
+	case handleable:
+		*reterr = MatchE(r.err, cases...)
+	default:
+		panic(r)
+	}
+}
@@ -0,0 +1,58 @@
+package errors
+
+/*
+	errors.Is and errors.As analogues which ignore suppressed errors
+*/
+
+import "reflect"
+
+// IsPrimaryE is the same as IsE, but suppressed errors added with WrapSuppressedVisibleE are not matched.
+// I.e. only the primary chain of err is examined.
+func IsPrimaryE(err, target error) bool {
+	if target == nil {
+		return err == target
+	}
+
+	isComparable := reflect.TypeOf(target).Comparable()
+	return !walkE(err, func(e error) bool {
+		if isComparable && e == target {
+			return false
+		}
+		if _, ok := e.(*_errorSuppressed); ok {
+			return true
+		}
+		x, ok := e.(interface{ Is(error) bool })
+		return !ok || !x.Is(target)
+	})
+}
+
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+
+// AsPrimaryE is the same as AsE, but suppressed errors added with WrapSuppressedVisibleE are not matched.
+// I.e. only the primary chain of err is examined.
+func AsPrimaryE(err error, target interface{}) bool {
+	if target == nil {
+		panic("errors: target cannot be nil")
+	}
+	val := reflect.ValueOf(target)
+	typ := val.Type()
+	if typ.Kind() != reflect.Ptr || val.IsNil() {
+		panic("errors: target must be a non-nil pointer")
+	}
+	targetType := typ.Elem()
+	if targetType.Kind() != reflect.Interface && !targetType.Implements(errorType) {
+		panic("errors: *target must be interface or implement error")
+	}
+
+	return !walkE(err, func(e error) bool {
+		if reflect.TypeOf(e).AssignableTo(targetType) {
+			val.Elem().Set(reflect.ValueOf(e))
+			return false
+		}
+		if _, ok := e.(*_errorSuppressed); ok {
+			return true
+		}
+		x, ok := e.(interface{ As(interface{}) bool })
+		return !ok || !x.As(target)
+	})
+}
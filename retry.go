@@ -0,0 +1,149 @@
+package errors
+
+/*
+	classification of errors by whether the failed operation can be retried and retrying itself
+*/
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"time"
+)
+
+var (
+	retryableKey  = NewKey[bool]("errors.retryable")
+	retryAfterKey = NewKey[time.Duration]("errors.retry_after")
+)
+
+func init() {
+	SetValuePolicy(retryableKey, KeepOutermost)
+	SetValuePolicy(retryAfterKey, KeepOutermost)
+	RegisterKey(retryableKey)
+	RegisterKey(retryAfterKey)
+}
+
+// ORetryable marks an error as retryable, i.e. the failed operation can be retried. See IsRetryableE.
+func ORetryable() OptionE {
+	return OKeyValue(retryableKey, true)
+}
+
+// OPermanent marks an error as permanent, i.e. the failed operation can't be retried. See IsRetryableE.
+func OPermanent() OptionE {
+	return OKeyValue(retryableKey, false)
+}
+
+// ORetryAfter marks an error as retryable after at least d. See RetryAfterE.
+func ORetryAfter(d time.Duration) OptionE {
+	return func(err error, _ int) error {
+		err = WithValue(err, retryableKey, true)
+		return WithValue(err, retryAfterKey, d)
+	}
+}
+
+// IsRetryableE reports whether the operation failed with err can be retried.
+// If err is marked with ORetryable, ORetryAfter or OPermanent, the outermost mark is used.
+// Otherwise err is retryable if it or any error wrapped in it has
+// Temporary() or Timeout() method returning true, e.g. net.Error.
+func IsRetryableE(err error) bool {
+	if retryable, ok := classification(err); ok {
+		return retryable
+	}
+	return !walkE(err, func(e error) bool {
+		if x, ok := e.(interface{ Temporary() bool }); ok && x.Temporary() {
+			return false
+		}
+		if x, ok := e.(interface{ Timeout() bool }); ok && x.Timeout() {
+			return false
+		}
+		return true
+	})
+}
+
+// IsPermanentE reports whether err is marked with OPermanent, and it's not overridden by outer marks.
+func IsPermanentE(err error) bool {
+	retryable, ok := classification(err)
+	return ok && !retryable
+}
+
+// RetryAfterE returns duration added to err by ORetryAfter. If there are several of them, the outermost one is returned.
+// found is false if there is no such.
+func RetryAfterE(err error) (d time.Duration, found bool) {
+	return Value(err, retryAfterKey)
+}
+
+func classification(err error) (retryable, found bool) {
+	return Value(err, retryableKey)
+}
+
+// RetryPolicy defines how many times and how often RetryE retries.
+// Delay between attempts grows exponentially: InitialInterval, InitialInterval*Multiplier,
+// InitialInterval*Multiplier^2 and so on up to MaxInterval. Each delay is randomized by Jitter.
+type RetryPolicy struct {
+	MaxAttempts     int           // maximum number of attempts including the first one, unlimited if not positive
+	InitialInterval time.Duration // delay after the first attempt, 100ms if not positive
+	MaxInterval     time.Duration // maximum delay, unlimited if not positive
+	Multiplier      float64       // growth factor of the delay, 2 if less than 1
+	Jitter          float64       // delay d is randomly chosen from [d*(1-Jitter), d*(1+Jitter)], should be in [0, 1]
+}
+
+// RetryE calls f until it succeeds, returns permanent error (see IsPermanentE),
+// policy allows no more attempts or ctx is done. Delay isn't less than the one added to the error by ORetryAfter.
+// f might be composed of several steps, e.g. RetryE(ctx, p, func() error { return AnyFuncE(step1, step2) }).
+//
+// Returns nil if f succeeded, otherwise the error of the last attempt with errors of previous attempts suppressed
+// in order of attempts. Each error is annotated with the number of its attempt and the returned one with the number
+// of attempts made, so SprintE prints the whole retry history.
+// If ctx is done, ctx.Err() is suppressed as well, before errors of the attempts.
+func RetryE(ctx context.Context, p RetryPolicy, f func() error) error {
+	if p.InitialInterval <= 0 {
+		p.InitialInterval = 100 * time.Millisecond
+	}
+	if p.Multiplier < 1 {
+		p.Multiplier = 2
+	}
+
+	var failed []error
+	delay := p.InitialInterval
+	for attempt := 1; ; attempt++ {
+		err := f()
+		if err == nil {
+			return nil
+		}
+		err = ToSkipE(1).WrapE(err, OAnno(fmt.Sprintf("attempt %d", attempt)))
+
+		if IsPermanentE(err) || attempt == p.MaxAttempts {
+			return ToSkipE(1).WrapE(retryError(err, failed, nil),
+				OAnno(fmt.Sprintf("failed after %d attempts", attempt)))
+		}
+
+		wait := time.Duration(float64(delay) * (1 + p.Jitter*(2*rand.Float64()-1)))
+		if after, ok := RetryAfterE(err); ok && after > wait {
+			wait = after
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ToSkipE(1).WrapE(retryError(err, failed, ctx.Err()),
+				OAnno(fmt.Sprintf("failed after %d attempts", attempt)))
+		case <-timer.C:
+		}
+
+		failed = append(failed, err)
+		delay = time.Duration(float64(delay) * p.Multiplier)
+		if p.MaxInterval > 0 && delay > p.MaxInterval {
+			delay = p.MaxInterval
+		}
+	}
+}
+
+func retryError(last error, failed []error, errCtx error) error {
+	for i := len(failed) - 1; i >= 0; i-- {
+		last = WrapSuppressedE(last, failed[i])
+	}
+	if errCtx != nil {
+		last = WrapSuppressedE(last, errCtx)
+	}
+	return last
+}
@@ -0,0 +1,105 @@
+package errors
+
+import (
+	"context"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRetryable(t *testing.T) {
+	timeout := &net.OpError{Op: "dial", Err: context.DeadlineExceeded}
+
+	tests := []struct {
+		err           error
+		wantRetryable bool
+		wantPermanent bool
+		wantAfter     time.Duration
+	}{
+		{io.EOF, false, false, 0},
+		{WrapE(io.EOF, ORetryable()), true, false, 0},
+		{WrapE(io.EOF, OPermanent()), false, true, 0},
+		{WrapE(io.EOF, ORetryable(), OAnno("a"), OPermanent()), false, true, 0},
+		{WrapE(io.EOF, OPermanent(), ORetryAfter(time.Second)), true, false, time.Second},
+		{WrapE(io.EOF, ORetryAfter(time.Second), ORetryAfter(time.Minute)), true, false, time.Minute},
+		{WrapE(timeout, OStack()), true, false, 0},
+		{WrapE(timeout, OPermanent()), false, true, 0},
+	}
+	for i, tt := range tests {
+		if got := IsRetryableE(tt.err); got != tt.wantRetryable {
+			t.Errorf("%d: wrong IsRetryableE: got %v, want %v", i, got, tt.wantRetryable)
+		}
+		// marks survive encoding, unlike Temporary() and Timeout() methods
+		for _, err := range []error{tt.err, mustDecodeE(t, mustEncodeJSON(t, tt.err))} {
+			if got := IsPermanentE(err); got != tt.wantPermanent {
+				t.Errorf("%d: wrong IsPermanentE: got %v, want %v", i, got, tt.wantPermanent)
+			}
+			if got, found := RetryAfterE(err); got != tt.wantAfter || found != (tt.wantAfter != 0) {
+				t.Errorf("%d: wrong RetryAfterE: got %v, %v, want %v", i, got, found, tt.wantAfter)
+			}
+		}
+	}
+}
+
+func TestRetryE(t *testing.T) {
+	p := RetryPolicy{MaxAttempts: 3, InitialInterval: time.Millisecond, Jitter: 0.5}
+
+	attempts := 0
+	failing := func(errs ...error) func() error {
+		return func() error {
+			attempts++
+			if attempts > len(errs) {
+				return nil
+			}
+			return WrapStackE(errs[attempts-1])
+		}
+	}
+
+	if err := RetryE(context.Background(), p, failing(io.EOF, io.EOF)); err != nil || attempts != 3 {
+		t.Errorf("Wrong result of successful retry: %v, attempts %d", err, attempts)
+	}
+
+	attempts = 0
+	err := RetryE(context.Background(), p, failing(io.EOF, io.ErrUnexpectedEOF, io.ErrClosedPipe, io.EOF))
+	if attempts != 3 || !IsE(err, io.ErrClosedPipe) {
+		t.Errorf("Wrong error: %v, attempts %d", err, attempts)
+	}
+	if s := SuppressedE(err); len(s) != 2 || !IsE(s[0], io.EOF) || !IsE(s[1], io.ErrUnexpectedEOF) {
+		t.Errorf("Wrong suppressed: %v", s)
+	}
+	sprint := SprintE(err)
+	for _, want := range []string{"ANNOTATION: attempt 1", "ANNOTATION: attempt 2", "ANNOTATION: attempt 3", "ANNOTATION: failed after 3 attempts"} {
+		if !strings.Contains(sprint, want) {
+			t.Errorf("SprintE doesn't contain %q:\n%s", want, sprint)
+		}
+	}
+	if strings.Contains(sprint, "ELSE ANNOTATIONS") {
+		t.Errorf("Annotations are not printed along with stack:\n%s", sprint)
+	}
+
+	attempts = 0
+	err = RetryE(context.Background(), p, failing(io.EOF, WrapE(io.ErrUnexpectedEOF, OPermanent()), io.EOF))
+	if attempts != 2 || !IsE(err, io.ErrUnexpectedEOF) {
+		t.Errorf("Permanent error is retried: %v, attempts %d", err, attempts)
+	}
+
+	attempts = 0
+	start := time.Now()
+	err = RetryE(context.Background(), p, failing(WrapE(io.EOF, ORetryAfter(50*time.Millisecond))))
+	if err != nil || time.Since(start) < 50*time.Millisecond {
+		t.Errorf("RetryAfter is not honored: %v, %v", err, time.Since(start))
+	}
+
+	attempts = 0
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	err = RetryE(ctx, RetryPolicy{InitialInterval: time.Hour}, failing(io.EOF, io.EOF))
+	if attempts != 1 || !IsE(err, io.EOF) {
+		t.Errorf("Wrong error of canceled retry: %v, attempts %d", err, attempts)
+	}
+	if s := SuppressedE(err); len(s) != 1 || s[0] != context.DeadlineExceeded {
+		t.Errorf("Wrong suppressed of canceled retry: %v", s)
+	}
+}
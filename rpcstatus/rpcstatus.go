@@ -0,0 +1,260 @@
+/*
+Package rpcstatus converts errors enriched by github.com/pashaosipyants/errors/v2 to gRPC-style statuses and back.
+It doesn't depend on gRPC itself, Status mirrors google.rpc.Status and can be transferred by any transport,
+e.g. encoded to json.
+
+Status of an error has a detail with the whole error encoded by errors.EncodeJSON, so that the error restored
+from the status by Status.Err keeps its stacktrace, annotations, values and suppressed errors.
+Registered sentinel errors (errors.RegisterSentinelE) are matched by errors.IsE after such round trip as well.
+*/
+package rpcstatus
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/pashaosipyants/errors/v2"
+)
+
+// Code is a status code. Values are the same as gRPC ones.
+type Code uint32
+
+const (
+	OK                 Code = 0
+	Canceled           Code = 1
+	Unknown            Code = 2
+	InvalidArgument    Code = 3
+	DeadlineExceeded   Code = 4
+	NotFound           Code = 5
+	AlreadyExists      Code = 6
+	PermissionDenied   Code = 7
+	ResourceExhausted  Code = 8
+	FailedPrecondition Code = 9
+	Aborted            Code = 10
+	OutOfRange         Code = 11
+	Unimplemented      Code = 12
+	Internal           Code = 13
+	Unavailable        Code = 14
+	DataLoss           Code = 15
+	Unauthenticated    Code = 16
+)
+
+var codeNames = [...]string{
+	OK:                 "OK",
+	Canceled:           "Canceled",
+	Unknown:            "Unknown",
+	InvalidArgument:    "InvalidArgument",
+	DeadlineExceeded:   "DeadlineExceeded",
+	NotFound:           "NotFound",
+	AlreadyExists:      "AlreadyExists",
+	PermissionDenied:   "PermissionDenied",
+	ResourceExhausted:  "ResourceExhausted",
+	FailedPrecondition: "FailedPrecondition",
+	Aborted:            "Aborted",
+	OutOfRange:         "OutOfRange",
+	Unimplemented:      "Unimplemented",
+	Internal:           "Internal",
+	Unavailable:        "Unavailable",
+	DataLoss:           "DataLoss",
+	Unauthenticated:    "Unauthenticated",
+}
+
+func (c Code) String() string {
+	if int(c) < len(codeNames) {
+		return codeNames[c]
+	}
+	return fmt.Sprintf("Code(%d)", uint32(c))
+}
+
+// Detail is a structured detail of a status, the analogue of google.protobuf.Any.
+type Detail struct {
+	TypeURL string          `json:"type_url"`
+	Value   json.RawMessage `json:"value"`
+}
+
+// ErrorDetailType is the type url of the detail, which holds an error encoded by errors.EncodeJSON.
+const ErrorDetailType = "type.googleapis.com/pashaosipyants.errors.v2.Error"
+
+// Status is the analogue of google.rpc.Status.
+type Status struct {
+	Code    Code     `json:"code"`
+	Message string   `json:"message"`
+	Details []Detail `json:"details,omitempty"`
+}
+
+// New creates status without details.
+func New(c Code, msg string) *Status {
+	return &Status{Code: c, Message: msg}
+}
+
+// FromError converts err to status. Returns nil if err is nil.
+// If err is restored from a status by Status.Err, this status is returned.
+// Code of the status is found by CodeE. Message is err.Error().
+// The whole error is saved to a detail of type ErrorDetailType.
+func FromError(err error) *Status {
+	if err == nil {
+		return nil
+	}
+	var se *statusError
+	if errors.AsPrimaryE(err, &se) && se == err {
+		return se.s
+	}
+
+	s := New(CodeE(err), err.Error())
+	if data, errEnc := errors.EncodeJSON(err); errEnc == nil {
+		s.Details = append(s.Details, Detail{TypeURL: ErrorDetailType, Value: data})
+	}
+	return s
+}
+
+// Err converts status back to an error. Returns nil if code is OK.
+// Error is restored from a detail of type ErrorDetailType by errors.DecodeE, if there is such.
+// Otherwise it's a new error with the message of the status.
+// Status can be retrieved from the error with FromError, its code with CodeE.
+func (s *Status) Err() error {
+	if s == nil || s.Code == OK {
+		return nil
+	}
+
+	var err error
+	for _, d := range s.Details {
+		if d.TypeURL != ErrorDetailType {
+			continue
+		}
+		if decoded, errDec := errors.DecodeE(d.Value); errDec == nil && decoded != nil {
+			err = decoded
+			break
+		}
+	}
+	if err == nil {
+		err = errors.NewE(s.Message)
+	}
+	return &statusError{s: s, err: err}
+}
+
+func (s *Status) String() string {
+	return fmt.Sprintf("rpc error: code = %s desc = %s", s.Code, s.Message)
+}
+
+// statusError is an error restored from a status.
+type statusError struct {
+	s   *Status
+	err error
+}
+
+func (e *statusError) Error() string {
+	return e.err.Error()
+}
+
+func (e *statusError) Unwrap() error {
+	return e.err
+}
+
+var mappings struct {
+	sync.RWMutex
+	codes     map[*errors.Code]Code
+	sentinels []sentinelCode
+}
+
+type sentinelCode struct {
+	target error
+	code   Code
+}
+
+// MapCode maps errors with the error code c (see errors.CodeE) to the status code.
+func MapCode(c *errors.Code, code Code) {
+	mappings.Lock()
+	defer mappings.Unlock()
+	if mappings.codes == nil {
+		mappings.codes = make(map[*errors.Code]Code)
+	}
+	mappings.codes[c] = code
+}
+
+// MapSentinel maps errors, which are target in terms of errors.IsPrimaryE, to the status code.
+// Suppressed errors are not taken into account, even visible ones.
+// Mappings are checked in order of adding.
+func MapSentinel(target error, code Code) {
+	mappings.Lock()
+	defer mappings.Unlock()
+	mappings.sentinels = append(mappings.sentinels, sentinelCode{target, code})
+}
+
+// CodeE returns status code of err. It's the first found of:
+//  1. OK if err is nil
+//  2. code of the status err is restored from, see Status.Err
+//  3. Canceled or DeadlineExceeded for errors of context package
+//  4. code mapped to the error code of err by MapCode
+//  5. code mapped to the sentinel error err is by MapSentinel
+//  6. code corresponding to the http status of the error code of err, see errors.Code.HTTPStatus
+//  7. Unknown
+func CodeE(err error) Code {
+	if err == nil {
+		return OK
+	}
+	var se *statusError
+	if errors.AsPrimaryE(err, &se) {
+		return se.s.Code
+	}
+	switch {
+	case errors.IsPrimaryE(err, context.Canceled):
+		return Canceled
+	case errors.IsPrimaryE(err, context.DeadlineExceeded):
+		return DeadlineExceeded
+	}
+
+	errCode := errors.CodeE(err)
+
+	mappings.RLock()
+	defer mappings.RUnlock()
+	if code, ok := mappings.codes[errCode]; ok && errCode != nil {
+		return code
+	}
+	for _, m := range mappings.sentinels {
+		if errors.IsPrimaryE(err, m.target) {
+			return m.code
+		}
+	}
+	if errCode != nil {
+		return fromHTTPStatus(errCode.HTTPStatus())
+	}
+	return Unknown
+}
+
+func fromHTTPStatus(status int) Code {
+	switch status {
+	case http.StatusOK:
+		return OK
+	case http.StatusBadRequest:
+		return InvalidArgument
+	case http.StatusUnauthorized:
+		return Unauthenticated
+	case http.StatusForbidden:
+		return PermissionDenied
+	case http.StatusNotFound:
+		return NotFound
+	case http.StatusConflict:
+		return AlreadyExists
+	case http.StatusPreconditionFailed:
+		return FailedPrecondition
+	case http.StatusRequestedRangeNotSatisfiable:
+		return OutOfRange
+	case http.StatusTooManyRequests:
+		return ResourceExhausted
+	case 499: // client closed request
+		return Canceled
+	case http.StatusInternalServerError:
+		return Internal
+	case http.StatusNotImplemented:
+		return Unimplemented
+	case http.StatusServiceUnavailable:
+		return Unavailable
+	case http.StatusGatewayTimeout:
+		return DeadlineExceeded
+	default:
+		return Unknown
+	}
+}
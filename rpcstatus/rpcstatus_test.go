@@ -0,0 +1,130 @@
+package rpcstatus
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/pashaosipyants/errors/v2"
+)
+
+var codeTestForbidden = errors.RegisterCode("rpcstatus_test_forbidden", 403001, "forbidden", http.StatusForbidden)
+
+func init() {
+	errors.RegisterSentinelE("sql.ErrNoRows", sql.ErrNoRows)
+	MapSentinel(sql.ErrNoRows, NotFound)
+}
+
+// serve is a loopback server, which reads method name and responds with the status of the error returned by handler.
+func serve(conn net.Conn, handler func(method string) error) {
+	defer conn.Close()
+	var method string
+	if err := json.NewDecoder(conn).Decode(&method); err != nil {
+		return
+	}
+	_ = json.NewEncoder(conn).Encode(FromError(handler(method)))
+}
+
+// call is a client of the loopback server.
+func call(t *testing.T, handler func(method string) error, method string) error {
+	client, server := net.Pipe()
+	defer client.Close()
+	go serve(server, handler)
+
+	if err := json.NewEncoder(client).Encode(method); err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	var s *Status
+	if err := json.NewDecoder(client).Decode(&s); err != nil {
+		t.Fatalf("Response failed: %v", err)
+	}
+	return s.Err()
+}
+
+func TestLoopback(t *testing.T) {
+	handler := func(method string) error {
+		switch method {
+		case "ok":
+			return nil
+		case "not_found":
+			return errors.WrapE(sql.ErrNoRows,
+				errors.OStack(),
+				errors.OAnno("task 1"),
+				errors.OValue("task", "1"),
+				errors.OSupp(io.EOF))
+		case "forbidden":
+			return errors.Error("access denied", errors.OCode(codeTestForbidden))
+		default:
+			return context.DeadlineExceeded
+		}
+	}
+
+	if err := call(t, handler, "ok"); err != nil {
+		t.Errorf("Wrong error of ok: %v", err)
+	}
+
+	err := call(t, handler, "not_found")
+	if CodeE(err) != NotFound || err.Error() != sql.ErrNoRows.Error() {
+		t.Errorf("Wrong error: %v, code %v", err, CodeE(err))
+	}
+	if !errors.IsE(err, sql.ErrNoRows) {
+		t.Error("Sentinel is not matched after round trip")
+	}
+	if errors.ValueE(err, "task") != "1" {
+		t.Errorf("Wrong value: %v", errors.ValueE(err, "task"))
+	}
+	if s := errors.SuppressedE(err); len(s) != 1 || s[0].Error() != io.EOF.Error() {
+		t.Errorf("Wrong suppressed: %v", s)
+	}
+	if sprint := errors.SprintE(err); !strings.Contains(sprint, "REMOTE STACK:") ||
+		!strings.Contains(sprint, "ANNOTATION: task 1") {
+		t.Errorf("Details are lost:\n%s", sprint)
+	}
+
+	err = call(t, handler, "forbidden")
+	if CodeE(err) != PermissionDenied || errors.CodeE(err) != codeTestForbidden {
+		t.Errorf("Wrong code: %v, %v", CodeE(err), errors.CodeE(err))
+	}
+
+	err = call(t, handler, "timeout")
+	if CodeE(err) != DeadlineExceeded {
+		t.Errorf("Wrong code: %v", CodeE(err))
+	}
+}
+
+func TestStatus(t *testing.T) {
+	if FromError(nil) != nil || CodeE(nil) != OK {
+		t.Error("Wrong status of nil error")
+	}
+	if New(OK, "").Err() != nil {
+		t.Error("OK status is an error")
+	}
+
+	s := New(Unavailable, "try later")
+	err := s.Err()
+	if err.Error() != "try later" || CodeE(err) != Unavailable || FromError(err) != s {
+		t.Errorf("Wrong error of status without details: %v", err)
+	}
+	if CodeE(errors.WrapE(err, errors.OAnno("a"))) != Unavailable {
+		t.Error("Code of wrapped status error is lost")
+	}
+	if s.String() != "rpc error: code = Unavailable desc = try later" || Code(100).String() != "Code(100)" {
+		t.Errorf("Wrong string: %s", s)
+	}
+
+	MapCode(codeTestForbidden, Aborted)
+	defer MapCode(codeTestForbidden, PermissionDenied)
+	if c := CodeE(errors.WrapCodeE(io.EOF, codeTestForbidden)); c != Aborted {
+		t.Errorf("Wrong mapped code: %v", c)
+	}
+
+	// e.g. the rest of the errors of errors.Collector
+	if c := CodeE(errors.WrapE(io.EOF, errors.OSuppVisible(sql.ErrNoRows), errors.OSuppVisible(context.Canceled))); c != Unknown {
+		t.Errorf("Code of suppressed error is used: %v", c)
+	}
+}
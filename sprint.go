@@ -7,85 +7,129 @@ package errors
 import (
 	"fmt"
 	"io"
-	"runtime"
 	"strings"
 )
 
 // Returns detailed error description.
 // With error msg, stacktrace, annotations, and suppressed errors.
+// If err wraps several errors(e.g. created by errors.Join), each of them is printed as well with its own details.
 func SprintE(err error) string {
 	var b strings.Builder
 
 	_, _ = fmt.Fprintln(&b, "ERROR:")
 	_, _ = fmt.Fprintln(&b, err)
 
-	var (
-		errAnno     *_errorAnnotation
-		annotations = make(map[string]string)
-		as          annotatedStack
-	)
-	for errIteration := err; AsE(errIteration, &errAnno); errIteration = errAnno.error {
-		annotations[errAnno.where] = errAnno.annotation
+	chain, branches := chainE(err)
+
+	var as annotatedStack
+	annos := annotationsOf(chain)
+	for i := len(annos) - 1; i >= 0; i-- {
+		as.annotations = append(as.annotations, annos[i]) // in order of adding
 	}
-	as.annotations = annotations
 
-	var errStack *_errorStack
-	if AsE(err, &errStack) {
+	if errStack := stackOf(chain); errStack != nil {
 		_, _ = fmt.Fprint(&b, "\n")
-		_, _ = fmt.Fprintln(&b, "STACK:")
-		as.stack = errStack.stack
+		if errStack.remote != nil {
+			_, _ = fmt.Fprintln(&b, "REMOTE STACK:")
+		} else {
+			_, _ = fmt.Fprintln(&b, "STACK:")
+		}
+		as.stack = errStack.trace()
+		as.depth = errStack.depth
 	}
 	_, _ = fmt.Fprint(&b, as)
 
-	supps := SuppressedE(err)
-	for _, s := range supps {
+	for _, e := range chain {
+		if errSupp, ok := e.(*_errorSuppressed); ok {
+			_, _ = fmt.Fprint(&b, "\n")
+			_, _ = fmt.Fprintln(&b, "SUPPRESSED:")
+			_, _ = fmt.Fprintln(&b, SprintE(errSupp.suppressed))
+		}
+	}
+
+	for _, br := range branches {
 		_, _ = fmt.Fprint(&b, "\n")
-		_, _ = fmt.Fprintln(&b, "SUPPRESSED:")
-		_, _ = fmt.Fprintln(&b, SprintE(s))
+		_, _ = fmt.Fprintln(&b, "JOINED:")
+		_, _ = fmt.Fprintln(&b, SprintE(br))
 	}
 
 	return b.String()
 }
 
 type annotatedStack struct {
-	stack       []uintptr
-	annotations map[string]string
+	stack       StackTrace
+	depth       int                 // number of frames from the first frame of the stack to the root of the goroutine, 0 if unknown
+	annotations []*_errorAnnotation // in order of adding
+}
+
+// frameOf returns index of the stack frame annotation a was added at or -1 if there is no such frame.
+// Frame is found by depth, so that annotations added in different invocations of a recursive function
+// are distinguished. If depth is unknown, e.g. the stack is truncated, the first frame of the function is used.
+func (s annotatedStack) frameOf(a *_errorAnnotation) int {
+	if s.depth == 0 || a.depth == 0 {
+		for i, f := range s.stack {
+			if f.Function == a.where {
+				return i
+			}
+		}
+		return -1
+	}
+	i := s.depth - a.depth
+	if i < 0 || i >= len(s.stack) || s.stack[i].Function != a.where {
+		return -1
+	}
+	return i
 }
 
 func (s annotatedStack) Format(st fmt.State, verb rune) {
-	for _, pc := range s.stack {
-		f := frame(pc)
-		_, _ = fmt.Fprint(st, f, "\n")
-		name := runtime.FuncForPC(f.pc()).Name()
-		if msgs, ok := s.annotations[name]; ok {
+	byFrame := make(map[int][]*_errorAnnotation)
+	var rest []*_errorAnnotation
+	for _, a := range s.annotations {
+		if i := s.frameOf(a); i >= 0 {
+			byFrame[i] = append(byFrame[i], a)
+		} else {
+			rest = append(rest, a)
+		}
+	}
+
+	for i, f := range s.stack {
+		if f.Function == "" {
+			_, _ = io.WriteString(st, "unknown\n")
+		} else {
+			_, _ = fmt.Fprintf(st, "%s\n\t%s:%d\n", f.Function, f.File, f.Line)
+		}
+		for _, a := range byFrame[i] {
 			_, _ = fmt.Fprint(st, "\tANNOTATION: ")
-			_, _ = fmt.Fprint(st, msgs, "\n")
-			delete(s.annotations, name)
+			_, _ = fmt.Fprint(st, a.annotation, "\n")
 		}
 	}
-	if len(s.annotations) != 0 {
+	if len(rest) != 0 {
 		_, _ = fmt.Fprint(st, "\nELSE ANNOTATIONS:")
 	}
-	for funcname, msgs := range s.annotations {
-		_, _ = fmt.Fprintf(st, "\n%s:", funcname)
-		_, _ = fmt.Fprint(st, msgs)
+	for _, a := range rest {
+		_, _ = fmt.Fprintf(st, "\n%s\n\t%s:%d\n", a.where, a.file, a.line)
+		_, _ = fmt.Fprint(st, "\tANNOTATION: ")
+		_, _ = fmt.Fprint(st, a.annotation)
 	}
 }
 
-// frame represents a program counter inside a stack frame.
-// For historical reasons if Frame is interpreted as a uintptr
-// its value represents the program counter + 1.
-type frame uintptr
-
-func (f frame) pc() uintptr { return uintptr(f) - 1 }
-
-func (f frame) Format(s fmt.State, verb rune) {
-	pc := f.pc()
-	fn := runtime.FuncForPC(pc)
-	if fn == nil {
-		io.WriteString(s, "unknown")
-	} else {
-		file, line := fn.FileLine(pc)
-		fmt.Fprintf(s, "%s\n\t%s:%d", fn.Name(), file, line)
+// formatE implements fmt.Formatter for error types of this package.
+// %s and %v print error message, %+v prints the same as SprintE,
+// %#v prints go syntax representation of the error with goSyntax.
+func formatE(s fmt.State, verb rune, err error, goSyntax func()) {
+	switch verb {
+	case 'v':
+		switch {
+		case s.Flag('+'):
+			_, _ = io.WriteString(s, SprintE(err))
+		case s.Flag('#'):
+			goSyntax()
+		default:
+			_, _ = io.WriteString(s, err.Error())
+		}
+	case 's':
+		_, _ = io.WriteString(s, err.Error())
+	case 'q':
+		_, _ = fmt.Fprintf(s, "%q", err.Error())
 	}
 }
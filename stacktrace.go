@@ -1,21 +1,162 @@
 package errors
 
-import "runtime"
+import (
+	"runtime"
+	"strings"
+	"sync/atomic"
+)
 
-// Maximum depth of stack recorded to an error.
-// One can change it, but be cautious.
-// Set value only in initialization part of a program before any error is created
-// not to cause concurrency problems.
+// Default maximum depth of stack recorded to an error.
+// Use SetStackPolicy to change it at runtime.
 const ErrStackMaxDepth = 32
 
+// StackPolicy controls how stacktraces are recorded by WrapStackE (and so OStack, Check etc.).
+type StackPolicy struct {
+	// MaxDepth is the maximum number of frames recorded. ErrStackMaxDepth is used if it's not positive.
+	MaxDepth int
+	// Disabled turns stacktrace recording off. WrapStackE returns err as is then.
+	Disabled bool
+	// Packages overrides the policy for stacktraces recorded in certain packages.
+	// Key is an import path of the package WrapStackE is called from, as in Frame.Package.
+	// Packages field of overriding policies is ignored.
+	Packages map[string]StackPolicy
+}
+
+var stackPolicy atomic.Value // *StackPolicy
+
+// SetStackPolicy sets policy of stacktraces recording. It's safe to call it concurrently with creation of errors.
+// Errors created earlier are not affected.
+func SetStackPolicy(p StackPolicy) {
+	packages := make(map[string]StackPolicy, len(p.Packages))
+	for pkg, pp := range p.Packages {
+		pp.Packages = nil
+		packages[pkg] = pp
+	}
+	p.Packages = packages
+	stackPolicy.Store(&p)
+}
+
+// GetStackPolicy returns current policy of stacktraces recording.
+func GetStackPolicy() StackPolicy {
+	p, _ := stackPolicy.Load().(*StackPolicy)
+	if p == nil {
+		return StackPolicy{}
+	}
+	packages := make(map[string]StackPolicy, len(p.Packages))
+	for pkg, pp := range p.Packages {
+		packages[pkg] = pp
+	}
+	res := *p
+	res.Packages = packages
+	return res
+}
+
+// policyFor returns policy applied to the stacktrace which starts with the given pc.
+func policyFor(pc uintptr) StackPolicy {
+	p, _ := stackPolicy.Load().(*StackPolicy)
+	if p == nil {
+		return StackPolicy{}
+	}
+	if len(p.Packages) != 0 {
+		if fn := runtime.FuncForPC(pc - 1); fn != nil {
+			if pp, ok := p.Packages[funcPackage(fn.Name())]; ok {
+				return pp
+			}
+		}
+	}
+	return *p
+}
+
 type stack []uintptr
 
 // callers with skip==0 returns stack of program counters starting from caller of callers
-// with greater skip it skips more stack frames starting from upper level of invocations
-func callers(skip int) stack {
-	var pcs [ErrStackMaxDepth]uintptr
-	n := runtime.Callers(2+skip, pcs[:])
-	pcsReduced := make([]uintptr, n)
-	copy(pcsReduced, pcs[:])
-	return pcsReduced
+// with greater skip it skips more stack frames starting from upper level of invocations.
+// depth is the number of frames from the first frame of the stack to the root of the goroutine,
+// it's 0 if the stack is truncated, so the depth is unknown.
+// Returns nil if recording is disabled by the stack policy.
+func callers(skip int) (st stack, depth int) {
+	var first [1]uintptr
+	if runtime.Callers(2+skip, first[:]) == 0 {
+		return nil, 0
+	}
+	p := policyFor(first[0])
+	if p.Disabled {
+		return nil, 0
+	}
+	maxDepth := p.MaxDepth
+	if maxDepth <= 0 {
+		maxDepth = ErrStackMaxDepth
+	}
+	pcs := make([]uintptr, maxDepth+1) // one more frame to find out whether the stack is truncated
+	n := runtime.Callers(2+skip, pcs)
+	if n > maxDepth {
+		return pcs[:maxDepth:maxDepth], 0
+	}
+	return pcs[:n:n], n
+}
+
+// stackDepth with skip==0 returns the number of frames from caller of stackDepth to the root of the goroutine.
+// It walks the whole stack, so use it only where the exact depth is required.
+func stackDepth(skip int) int {
+	pcs := make([]uintptr, 64)
+	for {
+		n := runtime.Callers(2+skip, pcs)
+		if n < len(pcs) {
+			return n
+		}
+		pcs = make([]uintptr, 2*len(pcs))
+	}
+}
+
+// Frame is one level of a stacktrace recorded to an error.
+type Frame struct {
+	Function string `json:"function"` // full function name, e.g. github.com/pashaosipyants/errors/v2.WrapE
+	File     string `json:"file"`
+	Line     int    `json:"line"`
+	Package  string `json:"package"`          // import path of the package the function belongs to
+	Remote   bool   `json:"remote,omitempty"` // frame is recorded in another process, see DecodeE
+}
+
+// StackTrace is a stacktrace recorded to an error. The first frame is the deepest one.
+type StackTrace []Frame
+
+func (s stack) trace() StackTrace {
+	st := make(StackTrace, 0, len(s))
+	for _, pc := range s {
+		st = append(st, frame(pc).resolve())
+	}
+	return st
+}
+
+// frame represents a program counter inside a stack frame.
+// For historical reasons if Frame is interpreted as a uintptr
+// its value represents the program counter + 1.
+type frame uintptr
+
+func (f frame) pc() uintptr { return uintptr(f) - 1 }
+
+// resolve finds function name, file, line and package of the frame.
+func (f frame) resolve() Frame {
+	pc := f.pc()
+	fn := runtime.FuncForPC(pc)
+	if fn == nil {
+		return Frame{}
+	}
+	file, line := fn.FileLine(pc)
+	return Frame{
+		Function: fn.Name(),
+		File:     file,
+		Line:     line,
+		Package:  funcPackage(fn.Name()),
+	}
+}
+
+// funcPackage extracts package import path from the full function name.
+// E.g. github.com/pashaosipyants/errors/v2_test.Example.func1 -> github.com/pashaosipyants/errors/v2_test
+func funcPackage(name string) string {
+	lastSlash := strings.LastIndex(name, "/")
+	if dot := strings.Index(name[lastSlash+1:], "."); dot >= 0 {
+		return name[:lastSlash+1+dot]
+	}
+	return name
 }
@@ -1,5 +1,7 @@
 package errors
 
+import "fmt"
+
 /*
 	error types which allow to add context to an error:
 		- stack - stacktrace
@@ -11,27 +13,42 @@ package errors
 type _errorStack struct {
 	error
 	stack
+	remote StackTrace // stacktrace of an error decoded by DecodeE, stack is empty then
+	depth  int        // number of frames from the first frame of the stack to the root of the goroutine, 0 if unknown
 }
 
 type _errorAnnotation struct {
 	error
-	where, annotation string // where defines at which level of stacktrace this annotation was added
+	where, annotation string  // where defines at which level of stacktrace this annotation was added
+	pc                uintptr // pc of the place annotation was added at
+	file              string
+	line              int
+	depth             int // number of frames from where to the root of the goroutine, 0 if unknown
 }
 
 type _errorSuppressed struct {
 	error
 	suppressed error
+	visible    bool // suppressed error is matched by IsE and AsE
 }
 
 type _errorValue struct {
 	error
 	key, value interface{}
+	appended   bool // value is a list of values accumulated according to AppendValues policy
 }
 
 func (e *_errorStack) Unwrap() error {
 	return e.error
 }
 
+func (e *_errorStack) trace() StackTrace {
+	if e.remote != nil {
+		return e.remote
+	}
+	return e.stack.trace()
+}
+
 func (e *_errorAnnotation) Unwrap() error {
 	return e.error
 }
@@ -40,6 +57,55 @@ func (e *_errorSuppressed) Unwrap() error {
 	return e.error
 }
 
+func (e *_errorSuppressed) Is(target error) bool {
+	return e.visible && IsE(e.suppressed, target)
+}
+
+func (e *_errorSuppressed) As(target interface{}) bool {
+	return e.visible && AsE(e.suppressed, target)
+}
+
 func (e *_errorValue) Unwrap() error {
 	return e.error
 }
+
+func (e *_errorStack) Format(s fmt.State, verb rune) {
+	formatE(s, verb, e, func() {
+		_, _ = fmt.Fprintf(s, "&errors._errorStack{error:%#v, stack:%#v, depth:%d}", e.error, e.trace(), e.depth)
+	})
+}
+
+func (e *_errorAnnotation) Format(s fmt.State, verb rune) {
+	formatE(s, verb, e, func() {
+		_, _ = fmt.Fprintf(s, "&errors._errorAnnotation{error:%#v, where:%q, annotation:%q, pc:%#x, file:%q, line:%d, depth:%d}",
+			e.error, e.where, e.annotation, e.pc, e.file, e.line, e.depth)
+	})
+}
+
+func (e *_errorSuppressed) Format(s fmt.State, verb rune) {
+	formatE(s, verb, e, func() {
+		_, _ = fmt.Fprintf(s, "&errors._errorSuppressed{error:%#v, suppressed:%#v, visible:%t}", e.error, e.suppressed, e.visible)
+	})
+}
+
+func (e *_errorValue) Format(s fmt.State, verb rune) {
+	formatE(s, verb, e, func() {
+		_, _ = fmt.Fprintf(s, "&errors._errorValue{error:%#v, key:%#v, value:%#v, appended:%t}", e.error, e.key, e.value, e.appended)
+	})
+}
+
+func (e *_errorStack) MarshalJSON() ([]byte, error) {
+	return EncodeJSON(e)
+}
+
+func (e *_errorAnnotation) MarshalJSON() ([]byte, error) {
+	return EncodeJSON(e)
+}
+
+func (e *_errorSuppressed) MarshalJSON() ([]byte, error) {
+	return EncodeJSON(e)
+}
+
+func (e *_errorValue) MarshalJSON() ([]byte, error) {
+	return EncodeJSON(e)
+}
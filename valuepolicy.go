@@ -0,0 +1,48 @@
+package errors
+
+import (
+	"reflect"
+	"sync"
+)
+
+// ValuePolicy defines what WrapValueE does if a value for the given key already exists in the error.
+type ValuePolicy int
+
+const (
+	// KeepDeepest ignores the new value, so only the first one (from the most deep stack level) is saved.
+	// It's the default policy.
+	KeepDeepest ValuePolicy = iota
+	// KeepOutermost saves the new value, so ValueE returns it. Older values are shadowed, see ValuesE.
+	KeepOutermost
+	// AppendValues saves all values. ValueE returns []interface{} with values in order of adding.
+	// Values added to the error by any other policy are included in the list as well.
+	AppendValues
+)
+
+var valuePolicies sync.Map // key -> ValuePolicy
+
+// SetValuePolicy sets policy of WrapValueE(and OValue) for the key.
+// Usually it's called in the initialization part of a program.
+// Panics if key is not comparable.
+func SetValuePolicy(key interface{}, p ValuePolicy) {
+	if !isComparable(key) {
+		panic("errors: SetValuePolicy: key is not comparable")
+	}
+	valuePolicies.Store(key, p)
+}
+
+// valuePolicyOf returns policy set for the key. Not comparable keys always have the default policy.
+func valuePolicyOf(key interface{}) ValuePolicy {
+	if !isComparable(key) {
+		return KeepDeepest
+	}
+	if p, ok := valuePolicies.Load(key); ok {
+		return p.(ValuePolicy)
+	}
+	return KeepDeepest
+}
+
+// isComparable reports whether key can be used as a map key.
+func isComparable(key interface{}) bool {
+	return key == nil || reflect.TypeOf(key).Comparable()
+}
@@ -13,19 +13,25 @@ import "runtime"
 //
 // skip is optional param. First of variadic parameters is used, else are ignored.
 // skip specifies the number of stacktrace levels to skip. By default stacktrace starts with the caller of WrapStackE.
+//
+// Depth of the stacktrace and whether it's recorded at all is controlled by SetStackPolicy.
 func WrapStackE(err error, skip ...int) error {
 	if err == nil {
 		return nil
 	}
 
-	var errStack *_errorStack
-	if AsE(err, &errStack) {
+	if chain, _ := chainE(err); stackOf(chain) != nil {
 		return err
 
 	} else {
+		st, depth := callers(getSkip(skip) + 1)
+		if st == nil {
+			return err // recording is disabled by the stack policy
+		}
 		return &_errorStack{
 			error: err,
-			stack: callers(getSkip(skip) + 1),
+			stack: st,
+			depth: depth,
 		}
 	}
 }
@@ -36,6 +42,8 @@ func WrapStackE(err error, skip ...int) error {
 // UnwrapE(returnederr) == err.
 // The sense of annotation is that by SprintE it's printed along with corresponding stacktrace level.
 // So it's obvious in what place in code this annotation was added.
+// Several annotations added at the same level are all printed in order of adding.
+// Annotations can be retrieved with the AnnotationsE.
 //
 // skip is optional param. First of variadic parameters is used, else are ignored.
 // skip specifies the number of stacktrace levels to skip. By default stacktrace starts with the caller of WrapAnnotationE.
@@ -44,11 +52,16 @@ func WrapAnnotationE(err error, annotation string, skip ...int) error {
 		return nil
 	}
 
-	pc, _, _, _ := runtime.Caller(getSkip(skip) + 1)
+	pc, file, line, _ := runtime.Caller(getSkip(skip) + 1)
+	_, depth := callers(getSkip(skip) + 1) // bounded by the stack policy, as depth is needed only to match a stack frame
 	return &_errorAnnotation{
 		error:      err,
 		where:      runtime.FuncForPC(pc).Name(),
 		annotation: annotation,
+		pc:         pc,
+		file:       file,
+		line:       line,
+		depth:      depth,
 	}
 }
 
@@ -68,20 +81,64 @@ func WrapSuppressedE(err, suppressed error) error {
 	}
 }
 
+// WrapSuppressedVisibleE is the same as WrapSuppressedE, but suppressed error is visible to IsE and AsE.
+// I.e. IsE(returnederr, target) is true if either IsE(err, target) or IsE(suppressed, target) is true.
+// Use IsPrimaryE and AsPrimaryE to ignore suppressed errors.
+func WrapSuppressedVisibleE(err, suppressed error) error {
+	if err == nil {
+		return nil
+	}
+
+	return &_errorSuppressed{
+		error:      err,
+		suppressed: suppressed,
+		visible:    true,
+	}
+}
+
 // WrapValueE returns error with err wrapped in and value added.
 // returnederr.Error() will be the same as err.Error().
 // If err is nil returns nil.
 // UnwrapE(returnederr) == err.
 // Value can be retrieved from returnederr(or any wrappers of it) by specified key with the ValueE function.
+//
+// If a value for the given key already exists, it's resolved according to the policy set for the key
+// with SetValuePolicy. By default (KeepDeepest) the new value is ignored and err is returned as is.
 func WrapValueE(err error, key, value interface{}) error {
+	return WrapValuePolicyE(err, key, value, valuePolicyOf(key))
+}
+
+// WrapValuePolicyE is the same as WrapValueE, but policy p is used instead of the one set for the key.
+func WrapValuePolicyE(err error, key, value interface{}, p ValuePolicy) error {
 	if err == nil {
 		return nil
 	}
 
-	var errVal *_errorValue
-	for errIteration := err; AsE(errIteration, &errVal); errIteration = errVal.error {
-		if errVal.key == key {
-			return err // if a value for the given key already exists, do nothing
+	existing := lookupErrValue(err, key)
+	switch {
+	case existing == nil:
+		if p == AppendValues {
+			return &_errorValue{
+				error:    err,
+				key:      key,
+				value:    []interface{}{value},
+				appended: true,
+			}
+		}
+	case p == KeepDeepest:
+		return err // if a value for the given key already exists, do nothing
+	case p == AppendValues:
+		var values []interface{}
+		if existing.appended {
+			values = append(values, existing.value.([]interface{})...)
+		} else {
+			values = append(values, existing.value)
+		}
+		return &_errorValue{
+			error:    err,
+			key:      key,
+			value:    append(values, value),
+			appended: true,
 		}
 	}
 
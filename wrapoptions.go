@@ -31,12 +31,25 @@ func OSupp(suppressed error) OptionE {
 	}
 }
 
+func OSuppVisible(suppressed error) OptionE {
+	return func(err error, _ int) error {
+		return WrapSuppressedVisibleE(err, suppressed)
+	}
+}
+
 func OValue(key, value interface{}) OptionE {
 	return func(err error, _ int) error {
 		return WrapValueE(err, key, value)
 	}
 }
 
+// The same as OValue, but policy p is used instead of the one set for the key. See WrapValuePolicyE.
+func OValuePolicy(key, value interface{}, p ValuePolicy) OptionE {
+	return func(err error, _ int) error {
+		return WrapValuePolicyE(err, key, value, p)
+	}
+}
+
 type ToSkipE int
 
 // The same as WrapE, but skip can be specified to skip several stacktrace levels.